@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	comp "compressor/internal/compressing"
+	"compressor/internal/store"
+	"compressor/internal/utiles"
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var addQuiet bool
+var addCmd = &cobra.Command{
+	Use:   "add [flags] <file> <files|directories...>",
+	Short: "Add files to an existing archive",
+	Long: `Compress files and append them to the archive without recompressing
+its contents. A directory is added together with its name. Paths are
+named as by compress, so the added files must not already be in the archive.`,
+	Args:         cobra.MinimumNArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pathes, err := addPathes(args[1:])
+		if err != nil {
+			return err
+		}
+		archive, err := os.OpenFile(args[0], os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		defer archive.Close()
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		size, err := totalSize(pathes)
+		if err != nil {
+			return err
+		}
+		fallback := store.NewCompressor()
+		comps, err := selectCompressors(pathes, compressionArgs(), fallback)
+		if err != nil {
+			return err
+		}
+
+		// при прерывании архив закрывается, и AppendFiles возвращает прежний футер
+		defer cleanup("", ctx, archive)()
+
+		prog := utiles.NewProgress[int64](len(pathes))
+		if !addQuiet {
+			prog.ShowProgress(size)
+			defer prog.Close()
+		} else {
+			prog.Close()
+		}
+
+		contentSize, footerSize, err := comp.AppendFiles(selectDecompressor, comps, fallback, pathes, archive, prog)
+		if err != nil {
+			cmd.Println(color.RedString("Files can't be added."))
+			return err
+		}
+		if !addQuiet {
+			cmd.Printf("\nFooter size: %d bytes\n", footerSize)
+			cmd.Printf("Output file total size: %d bytes\n", contentSize+footerSize)
+		}
+		cmd.Println(color.GreenString("Files added!"))
+		return nil
+	},
+}
+
+func init() {
+	addCompressionFlags(addCmd)
+	addCmd.Flags().BoolVarP(&addQuiet, "quiet", "q", false, "quiet mode (no progress output)")
+}
+
+// addPathes раскрывает каталоги в список их содержимого вместе с самим
+// каталогом, чтобы его имя вошло в пути записей
+func addPathes(args []string) ([]string, error) {
+	var pathes []string
+	for _, arg := range args {
+		path, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, err
+		}
+		pathes = append(pathes, path)
+		if ok, _ := isDir(path); ok {
+			files, err := utiles.GetDirFiles(path)
+			if err != nil {
+				return nil, err
+			}
+			pathes = append(pathes, files...)
+		}
+	}
+	return pathes, nil
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	comp "compressor/internal/compressing"
+	"compressor/internal/store"
+	"io"
+	"maps"
+	"os"
+	"runtime"
+	"strconv"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	autoCompressionType = "auto"
+	autoSampleSize      = 256 << 10
+)
+
+// autoCandidates — типы сжатия, которые пробуются для каждого файла в режиме auto
+var autoCandidates = []string{
+	huffmanCompressionType, lzCompressionType, deflateCompressionType, arithCompressionType, bwtCompressionType,
+}
+
+// selectAutoCompressors выбирает компрессор для каждого файла по пробному
+// сжатию его начала. Файлы, которые не сжимаются, сохраняются как есть.
+// Файлы одного типа сжимаются компрессорами по режиму моделей.
+func selectAutoCompressors(
+	pathes []string, compArgs map[string]any, stored *store.Compressor,
+) ([]comp.CompressionBase, error) {
+	types := make([]string, len(pathes))
+	var eg errgroup.Group
+	eg.SetLimit(runtime.NumCPU())
+	for i, path := range pathes {
+		eg.Go(func() error {
+			info, err := os.Lstat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				// у каталогов и ссылок нет содержимого, компрессор им не нужен
+				return err
+			}
+			types[i], err = bestCompressionType(path, compArgs)
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string)
+	for i, t := range types {
+		if t != "" {
+			groups[t] = append(groups[t], pathes[i])
+		}
+	}
+	compressors := make(map[string]comp.CompressionBase, len(pathes))
+	for t, groupPathes := range groups {
+		if t == storeCompressionType {
+			for _, path := range groupPathes {
+				compressors[path] = stored
+			}
+			continue
+		}
+		groupCompressors, err := modelCompressors(t, groupPathes, compArgs)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(compressors, groupCompressors)
+	}
+
+	comps := make([]comp.CompressionBase, len(pathes))
+	for i, path := range pathes {
+		comps[i] = compressors[path]
+	}
+	return comps, nil
+}
+
+// bestCompressionType возвращает тип сжатия, дающий наименьший размер образца
+func bestCompressionType(path string, compArgs map[string]any) (string, error) {
+	sample, err := readSample(path, autoSampleSize)
+	if err != nil {
+		return "", err
+	}
+
+	best, bestSize := storeCompressionType, int64(len(sample))
+	for _, t := range autoCandidates {
+		c, err := trialCompressor(t, compArgs)
+		if err != nil {
+			return "", err
+		}
+		size, err := comp.TrialSize(c, sample)
+		if err != nil {
+			return "", err
+		}
+		if size < bestSize {
+			best, bestSize = t, size
+		}
+	}
+	return best, nil
+}
+
+// trialCompressor создает компрессор для пробного сжатия. Размер блока
+// Хаффмана подбирается только для итоговой группы файлов, для пробы
+// без явного --block используются однобайтовые символы.
+func trialCompressor(compType string, compArgs map[string]any) (comp.CompressionBase, error) {
+	if compType == huffmanCompressionType {
+		if _, err := strconv.Atoi(compArgs["block"].(string)); err != nil {
+			compArgs = maps.Clone(compArgs)
+			compArgs["block"] = "1"
+		}
+	}
+	return selectCompressor(compType, nil, compArgs, 0)
+}
+
+func readSample(path string, size int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sample := make([]byte, size)
+	n, err := io.ReadFull(f, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return sample[:n], nil
+}
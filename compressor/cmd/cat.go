@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bufio"
+	comp "compressor/internal/compressing"
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var catCmd = &cobra.Command{
+	Use:   "cat <file> <path>",
+	Short: "Write one file of the archive to stdout",
+	Long: `Decode one file of the archive to stdout and verify its checksum.
+Exits with status 2 if the checksum doesn't match.`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		out := bufio.NewWriter(cmd.OutOrStdout())
+		err = comp.DecompressEntry(selectDecompressor, src, args[1], out)
+		if flushErr := out.Flush(); err == nil {
+			err = flushErr
+		}
+		var mismatch *comp.ErrChecksumMismatch
+		if errors.As(err, &mismatch) {
+			return &ExitCodeError{exitChecksumMismatch, err}
+		}
+		return err
+	},
+}
@@ -1,159 +1,340 @@
-package cmd
-
-import (
-	comp "compressor/internal/compressing"
-	"compressor/internal/huffman"
-	"compressor/internal/utiles"
-	"context"
-	"errors"
-	"fmt"
-	"os"
-	"os/signal"
-	"path/filepath"
-	"strings"
-	"syscall"
-
-	"github.com/fatih/color"
-	"github.com/spf13/cobra"
-)
-
-const (
-	huffmanCompressionType = "huff"
-	OutputExt              = ".dedal"
-)
-
-var (
-	compBlockSize int
-	compType      string
-	compDestDir   string
-	compQuiet     bool
-)
-
-var compressCmd = &cobra.Command{
-	Use:   "compress [flags] <files|directory>",
-	Short: "Compress files or directories",
-	Args:  cobra.MinimumNArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) (err error) {
-		compDestDir, err = filepath.Abs(compDestDir)
-		if err != nil {
-			return err
-		}
-		if ok, err := isDir(compDestDir); !(ok || os.IsNotExist(err)) {
-			color.Red("--dest must be a directory")
-			return err
-		}
-		if err := os.MkdirAll(compDestDir, 0755); err != nil {
-			return err
-		}
-
-		pathes := args
-		if ok, _ := isDir(pathes[0]); ok && len(pathes) == 1 {
-			pathes, err = utiles.GetDirFiles(args[0])
-			if err != nil {
-				return err
-			}
-		}
-
-		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-		defer cancel()
-
-		compArgs := map[string]any{"blockSize": compBlockSize}
-		result, err := compression(pathes, compArgs, compDestDir, !compQuiet, ctx)
-		if err != nil {
-			if errors.Is(err, &comp.ErrCompression{}) {
-				cmd.Println(color.RedString("Compression failed"))
-			}
-			return err
-		}
-
-		compFilePath, err := makeCompressedFile(compDestDir, pathes[0], result.tempPath)
-		if err != nil {
-			return err
-		}
-		if !compQuiet {
-			cmd.Printf("\nOutput file: %s\n", color.GreenString(compFilePath))
-			cmd.Printf("Footer size: %d bytes\n", result.footerSize)
-			cmd.Printf("Output file total size: %d bytes\n", result.compSize+result.footerSize)
-		}
-		cmd.Println(color.GreenString("Compression succeeded!"))
-		return nil
-	},
-}
-
-func init() {
-	compressCmd.Flags().IntVar(&compBlockSize, "block", 0, "block size for compression")
-	compressCmd.Flags().StringVar(&compType, "type", huffmanCompressionType, "compression type")
-	compressCmd.Flags().StringVar(&compDestDir, "dest", "", "directory of output file")
-	compressCmd.Flags().BoolVarP(&compQuiet, "quiet", "q", false, "quiet mode (no progress output)")
-}
-
-func makeCompressedFile(path, name string, tempPath string) (finalPath string, err error) {
-	name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
-	finalPath = getUniqueName(filepath.Join(path, name) + OutputExt)
-	if err := os.MkdirAll(path, 0755); err != nil {
-		return "", err
-	}
-	if err := os.Rename(tempPath, finalPath); err != nil {
-		return "", err
-	}
-	return finalPath, nil
-}
-
-type compressionOutput struct {
-	tempPath   string
-	compSize   int64
-	footerSize int64
-}
-
-func compression(
-	pathes []string,
-	compArgs map[string]any,
-	dstDir string,
-	showProgress bool,
-	ctx context.Context,
-) (*compressionOutput, error) {
-
-	totalSizeVal, err := totalSize(pathes)
-	if err != nil {
-		return nil, err
-	}
-	var totalSize int64 = totalSizeVal
-
-	dstFile, err := os.CreateTemp(dstDir, "temp-comp-*.dedal-temp")
-	if err != nil {
-		return nil, err
-	}
-
-	result := &compressionOutput{
-		tempPath: dstFile.Name(),
-	}
-
-	defer dstFile.Close()
-
-	go cleanup(dstFile.Name(), ctx, dstFile)
-
-	prog := utiles.NewProgress[int64](len(pathes))
-	if showProgress {
-		prog.ShowProgress(totalSize)
-		defer prog.Close()
-	} else {
-		prog.Close()
-	}
-
-	var compSize, footerSize int64
-	switch compType {
-	case "huff":
-		compressor := huffman.NewCompressor(compArgs["blockSize"].(int), totalSize)
-		compSize, footerSize, err = comp.CompressFiles(compressor, pathes, dstFile, prog)
-		if err != nil {
-			return nil, err
-		}
-	default:
-		return nil, fmt.Errorf("unsupported compression type: %s", compType)
-	}
-
-	result.tempPath = dstFile.Name()
-	result.compSize = compSize
-	result.footerSize = footerSize
-	return result, nil
-}
+package cmd
+
+import (
+	"compressor/internal/arith"
+	"compressor/internal/bwt"
+	comp "compressor/internal/compressing"
+	"compressor/internal/deflate"
+	"compressor/internal/huffman"
+	"compressor/internal/lz"
+	"compressor/internal/store"
+	"compressor/internal/utiles"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+const (
+	huffmanCompressionType = "huff"
+	lzCompressionType      = "lz"
+	deflateCompressionType = "deflate"
+	arithCompressionType   = "arith"
+	bwtCompressionType     = "bwt"
+	storeCompressionType   = "store"
+	OutputExt              = ".dedal"
+)
+
+var (
+	compBlock      string
+	compMaxCodeLen uint8
+	compWindow     int
+	compLevel      int
+	compOrder      int
+	compBWTBlock   int
+	compType       string
+	compDestDir    string
+	compOutput     string
+	compQuiet      bool
+)
+
+var compressCmd = &cobra.Command{
+	Use:   "compress [flags] <files|directory>",
+	Short: "Compress files or directories",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		pathes := args
+		if ok, _ := isDir(pathes[0]); ok && len(pathes) == 1 {
+			pathes, err = utiles.GetDirFiles(args[0])
+			if err != nil {
+				return err
+			}
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		if compOutput == "-" {
+			return compressStream(cmd, pathes, ctx)
+		}
+
+		dstDir := compDestDir
+		if compOutput != "" {
+			dstDir = filepath.Dir(compOutput)
+		}
+		dstDir, err = filepath.Abs(dstDir)
+		if err != nil {
+			return err
+		}
+		if ok, err := isDir(dstDir); !(ok || os.IsNotExist(err)) {
+			color.Red("--dest must be a directory")
+			return err
+		}
+		if err := os.MkdirAll(dstDir, 0755); err != nil {
+			return err
+		}
+
+		result, err := compression(pathes, compressionArgs(), dstDir, !compQuiet, ctx)
+		if err != nil {
+			if errors.Is(err, &comp.ErrCompression{}) {
+				cmd.Println(color.RedString("Compression failed"))
+			}
+			return err
+		}
+
+		compFilePath := compOutput
+		if compOutput != "" {
+			err = os.Rename(result.tempPath, compOutput)
+		} else {
+			compFilePath, err = makeCompressedFile(dstDir, pathes[0], result.tempPath)
+		}
+		if err != nil {
+			return err
+		}
+		if !compQuiet {
+			cmd.Printf("\nOutput file: %s\n", color.GreenString(compFilePath))
+			if result.blockSize != 0 {
+				cmd.Printf("Block size: %d bytes (%s)\n", result.blockSize, compBlock)
+			}
+			cmd.Printf("Footer size: %d bytes\n", result.footerSize)
+			cmd.Printf("Output file total size: %d bytes\n", result.compSize+result.footerSize)
+		}
+		cmd.Println(color.GreenString("Compression succeeded!"))
+		return nil
+	},
+}
+
+func init() {
+	addCompressionFlags(compressCmd)
+	compressCmd.Flags().StringVar(&compDestDir, "dest", "", "directory of output file")
+	compressCmd.Flags().StringVarP(&compOutput, "output", "o", "", "output file, \"-\" writes a stream archive to stdout")
+	compressCmd.MarkFlagsMutuallyExclusive("dest", "output")
+	compressCmd.Flags().BoolVarP(&compQuiet, "quiet", "q", false, "quiet mode (no progress output)")
+}
+
+// addCompressionFlags регистрирует флаги выбора и настройки сжатия,
+// общие для compress и add
+func addCompressionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&compBlock, "block", string(huffman.BlockAuto),
+		"block size for compression: auto, sample or number of bytes",
+	)
+	cmd.Flags().Uint8Var(
+		&compMaxCodeLen, "max-code-len", huffman.MaxCodeLen, "maximum Huffman code length in bits",
+	)
+	cmd.Flags().IntVar(&compWindow, "window", lz.DefaultWindowSize, "LZ sliding window size in bytes")
+	cmd.Flags().IntVar(&compLevel, "level", deflate.DefaultLevel, "DEFLATE compression level (1-9)")
+	cmd.Flags().IntVar(&compOrder, "order", 0, "context order of the arithmetic coder model (0 or 1)")
+	cmd.Flags().IntVar(&compBWTBlock, "bwt-block", bwt.DefaultBlockSize, "BWT block size in bytes")
+	cmd.Flags().StringVar(
+		&compType, "type", huffmanCompressionType, "compression type: huff, lz, deflate, arith, bwt, store or auto",
+	)
+	addModelFlags(cmd)
+}
+
+// compressionArgs собирает параметры компрессоров из флагов
+func compressionArgs() map[string]any {
+	return map[string]any{
+		"block":      compBlock,
+		"maxCodeLen": compMaxCodeLen,
+		"window":     compWindow,
+		"level":      compLevel,
+		"order":      compOrder,
+		"bwtBlock":   compBWTBlock,
+	}
+}
+
+func makeCompressedFile(path, name string, tempPath string) (finalPath string, err error) {
+	name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	finalPath = getUniqueName(filepath.Join(path, name) + OutputExt)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tempPath, finalPath); err != nil {
+		return "", err
+	}
+	return finalPath, nil
+}
+
+type compressionOutput struct {
+	tempPath   string
+	compSize   int64
+	footerSize int64
+	blockSize  int
+}
+
+// huffmanBlockSize разбирает значение --block: число байт либо стратегию выбора
+func huffmanBlockSize(pathes []string, block string, maxCodeLen uint8) (int, error) {
+	if blockSize, err := strconv.Atoi(block); err == nil {
+		if blockSize <= 0 {
+			return 0, fmt.Errorf("block size must be positive: %d", blockSize)
+		}
+		return blockSize, nil
+	}
+	estimate, err := huffman.SelectBlockSize(pathes, huffman.BlockStrategy(block), maxCodeLen)
+	if err != nil {
+		return 0, err
+	}
+	return estimate.BlockSize, nil
+}
+
+// selectCompressor создает компрессор по типу сжатия.
+func selectCompressor(
+	compType string, pathes []string, compArgs map[string]any, totalSize int64,
+) (comp.CompressionBase, error) {
+	switch compType {
+	case huffmanCompressionType:
+		maxCodeLen := compArgs["maxCodeLen"].(uint8)
+		if maxCodeLen == 0 || maxCodeLen > huffman.MaxCodeLen {
+			return nil, fmt.Errorf("max code length must be in range [1, %d]", huffman.MaxCodeLen)
+		}
+		blockSize, err := huffmanBlockSize(pathes, compArgs["block"].(string), maxCodeLen)
+		if err != nil {
+			return nil, err
+		}
+		compressor := huffman.NewCompressor(blockSize, totalSize)
+		compressor.MaxCodeLen = maxCodeLen
+		return compressor, nil
+	case lzCompressionType:
+		return lz.NewCompressor(compArgs["window"].(int))
+	case deflateCompressionType:
+		return deflate.NewCompressor(compArgs["level"].(int))
+	case arithCompressionType:
+		return arith.NewCompressor(compArgs["order"].(int))
+	case bwtCompressionType:
+		return bwt.NewCompressor(compArgs["bwtBlock"].(int))
+	case storeCompressionType:
+		return store.NewCompressor(), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression type: %s", compType)
+	}
+}
+
+func compression(
+	pathes []string,
+	compArgs map[string]any,
+	dstDir string,
+	showProgress bool,
+	ctx context.Context,
+) (*compressionOutput, error) {
+
+	totalSizeVal, err := totalSize(pathes)
+	if err != nil {
+		return nil, err
+	}
+	var totalSize int64 = totalSizeVal
+
+	// файлы, которые не удается сжать, сохраняются без сжатия
+	fallback := store.NewCompressor()
+	comps, err := selectCompressors(pathes, compArgs, fallback)
+	if err != nil {
+		return nil, err
+	}
+
+	dstFile, err := os.CreateTemp(dstDir, "temp-comp-*.dedal-temp")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &compressionOutput{
+		tempPath: dstFile.Name(),
+	}
+	if compType == huffmanCompressionType {
+		for _, c := range comps {
+			if huff, ok := c.(*huffman.Compressor); ok {
+				result.blockSize = huff.BlockSize
+				break
+			}
+		}
+	}
+
+	defer dstFile.Close()
+
+	defer cleanup(dstFile.Name(), ctx, dstFile)()
+
+	prog := utiles.NewProgress[int64](len(pathes))
+	if showProgress {
+		prog.ShowProgress(totalSize)
+		defer prog.Close()
+	} else {
+		prog.Close()
+	}
+
+	compSize, footerSize, err := comp.CompressFilesWith(comps, fallback, pathes, dstFile, prog)
+	if err != nil {
+		return nil, err
+	}
+
+	result.tempPath = dstFile.Name()
+	result.compSize = compSize
+	result.footerSize = footerSize
+	return result, nil
+}
+
+// selectCompressors выбирает компрессор для каждого файла: по типу из
+// --type или пробным сжатием в режиме auto
+func selectCompressors(
+	pathes []string, compArgs map[string]any, fallback *store.Compressor,
+) ([]comp.CompressionBase, error) {
+	if compType == autoCompressionType {
+		return selectAutoCompressors(pathes, compArgs, fallback)
+	}
+	regular, err := regularFiles(pathes)
+	if err != nil {
+		return nil, err
+	}
+	compressors, err := modelCompressors(compType, regular, compArgs)
+	if err != nil {
+		return nil, err
+	}
+	comps := make([]comp.CompressionBase, len(pathes))
+	for i, path := range pathes {
+		comps[i] = compressors[path]
+	}
+	return comps, nil
+}
+
+// compressStream пишет потоковый архив в stdout, сообщения идут в stderr.
+// Данные файлов сначала сжимаются во временный файл.
+func compressStream(cmd *cobra.Command, pathes []string, ctx context.Context) error {
+	size, err := totalSize(pathes)
+	if err != nil {
+		return err
+	}
+	fallback := store.NewCompressor()
+	comps, err := selectCompressors(pathes, compressionArgs(), fallback)
+	if err != nil {
+		return err
+	}
+
+	spool, err := os.CreateTemp("", "temp-comp-*.dedal-temp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	defer cleanup(spool.Name(), ctx, spool)()
+
+	prog := utiles.NewProgress[int64](len(pathes))
+	if !compQuiet {
+		prog.ShowProgress(size)
+		defer prog.Close()
+	} else {
+		prog.Close()
+	}
+
+	if _, _, err := comp.CompressStream(comps, fallback, pathes, spool, cmd.OutOrStdout(), prog); err != nil {
+		cmd.Println(color.RedString("Compression failed"))
+		return err
+	}
+	cmd.Println(color.GreenString("Compression succeeded!"))
+	return nil
+}
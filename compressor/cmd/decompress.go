@@ -1,101 +1,234 @@
-package cmd
-
-import (
-	comp "compressor/internal/compressing"
-	"compressor/internal/huffman"
-	"compressor/internal/utiles"
-	"fmt"
-	"os"
-	"os/signal"
-	"path/filepath"
-	"strings"
-	"syscall"
-
-	"github.com/fatih/color"
-	"github.com/spf13/cobra"
-)
-
-// selectDecompressor возвращает декомпрессор по типу сжатия.
-func selectDecompressor(compType string) comp.Decompressor {
-	switch compType {
-	case huffman.CompressionType:
-		return huffman.NewDecompressor()
-	default:
-		return nil
-	}
-}
-
-var (
-	decompDest  string
-	decompQuiet bool
-)
-var uncompressCmd = &cobra.Command{
-	Use:   "uncompress [flags] <file>",
-	Short: "Decompress file",
-	Args:  cobra.MinimumNArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		if ok, err := isDir(decompDest); !(ok || os.IsNotExist(err)) {
-			cmd.Println(color.RedString("--dest must be directory"))
-			return err
-		}
-
-		ctx, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
-		defer cancel()
-
-		srcPath := args[0]
-		showProgress := !decompQuiet
-		dstDir := decompDest
-
-		srcFile, err := os.Open(srcPath)
-		if err != nil {
-			return err
-		}
-		defer srcFile.Close()
-
-		// if there is only one compressed file, then give it a base name
-		dstDir, err = filepath.Abs(getUniqueName(dstDir))
-		if err != nil {
-			cmd.Println("Failed to create destination directory")
-			return err
-		}
-		if err := os.MkdirAll(dstDir, 0755); err != nil {
-			cmd.Println("Failed to create destination directory")
-			return err
-		}
-		go cleanup(dstDir, ctx, srcFile)
-
-		prog := utiles.NewProgress[int64](0)
-		if info, err := srcFile.Stat(); showProgress && err == nil {
-			prog.ShowProgress(info.Size())
-			defer prog.Close()
-		} else {
-			prog.Close()
-		}
-
-		output, err := comp.Decompress(selectDecompressor, srcFile, dstDir, prog)
-		if err != nil {
-			cmd.Println(color.RedString("File can't be uncompressed! Decompression failed."))
-			return err
-		}
-		if showProgress {
-			cmd.Println("files: ")
-			for _, out := range output {
-				relPath := strings.TrimPrefix(out.Path, filepath.Clean(dstDir))
-
-				if out.NewChecksum != out.OldChecksum {
-					cmd.Println(color.RedString("Decompression failed."))
-					return fmt.Errorf("Checksum for %s mismatch! Decompression failed.\n", relPath)
-				}
-				cmd.Println(color.GreenString(".%s", relPath))
-			}
-		}
-
-		cmd.Println(color.GreenString("\nDecompression succeeded!"))
-		return nil
-	},
-}
-
-func init() {
-	uncompressCmd.Flags().StringVar(&decompDest, "dest", "", "output file or directory path")
-	uncompressCmd.Flags().BoolVarP(&decompQuiet, "quiet", "q", false, "quiet mode (no progress output)")
-}
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compressor/internal/arith"
+	"compressor/internal/bwt"
+	comp "compressor/internal/compressing"
+	"compressor/internal/deflate"
+	"compressor/internal/huffman"
+	"compressor/internal/lz"
+	"compressor/internal/store"
+	"compressor/internal/utiles"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// selectDecompressor возвращает декомпрессор по типу сжатия и версии формата.
+func selectDecompressor(compType string, version int) comp.Decompressor {
+	switch compType {
+	case huffman.CompressionType:
+		return huffman.NewDecompressor(version)
+	case lz.CompressionType:
+		return lz.NewDecompressor()
+	case deflate.CompressionType:
+		return deflate.NewDecompressor()
+	case arith.CompressionType:
+		return arith.NewDecompressor()
+	case bwt.CompressionType:
+		return bwt.NewDecompressor()
+	case store.CompressionType:
+		return store.NewDecompressor()
+	default:
+		return nil
+	}
+}
+
+var (
+	decompDest    string
+	decompQuiet   bool
+	decompNoPerms bool
+	decompNoOwner bool
+	decompUnsafe  bool
+	decompExclude []string
+
+	decompOnMismatch string
+)
+
+func parseMismatchPolicy(policy string) (comp.MismatchPolicy, error) {
+	switch policy {
+	case "fail":
+		return comp.MismatchFail, nil
+	case "keep":
+		return comp.MismatchKeep, nil
+	case "delete":
+		return comp.MismatchDelete, nil
+	default:
+		return 0, fmt.Errorf("invalid --on-mismatch %q: must be fail, keep or delete", policy)
+	}
+}
+
+var uncompressCmd = &cobra.Command{
+	Use:   "uncompress [flags] <file> [paths or patterns...]",
+	Short: "Decompress file",
+	Long: `Decompress file, "-" reads the archive from stdin. Paths or glob
+patterns after the archive name select the entries to extract: "**"
+matches any number of directories, a pattern without "/" matches names
+at any depth.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if ok, err := isDir(decompDest); !(ok || os.IsNotExist(err)) {
+			cmd.Println(color.RedString("--dest must be directory"))
+			return err
+		}
+		onMismatch, err := parseMismatchPolicy(decompOnMismatch)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		srcPath := args[0]
+		showProgress := !decompQuiet
+		dstDir := decompDest
+
+		srcFile := os.Stdin
+		if srcPath != "-" {
+			if srcFile, err = os.Open(srcPath); err != nil {
+				return err
+			}
+			defer srcFile.Close()
+		}
+
+		// if there is only one compressed file, then give it a base name
+		dstDir, err = filepath.Abs(getUniqueName(dstDir))
+		if err != nil {
+			cmd.Println("Failed to create destination directory")
+			return err
+		}
+		created, err := missingDirs(dstDir)
+		if err != nil {
+			cmd.Println("Failed to create destination directory")
+			return err
+		}
+		if err := os.MkdirAll(dstDir, 0755); err != nil {
+			cmd.Println("Failed to create destination directory")
+			return err
+		}
+		// при прерывании удаляется только каталог, созданный этим запуском;
+		// файлы в существующем каталоге удаляет сама распаковка
+		createdRoot := ""
+		if len(created) > 0 {
+			createdRoot = created[len(created)-1]
+		}
+		defer cleanup(createdRoot, ctx, srcFile)()
+
+		prog := utiles.NewProgress[int64](0)
+		if info, err := srcFile.Stat(); showProgress && err == nil && srcPath != "-" {
+			prog.ShowProgress(info.Size())
+			defer prog.Close()
+		} else {
+			prog.Close()
+		}
+
+		opts := comp.DecompressOptions{
+			OnMismatch:       onMismatch,
+			NoPerms:          decompNoPerms,
+			NoOwner:          decompNoOwner,
+			AllowUnsafePaths: decompUnsafe,
+			Include:          args[1:],
+			Exclude:          decompExclude,
+		}
+		var output []*comp.DecompressedFile
+		if srcPath == "-" {
+			output, err = decompressStdin(srcFile, dstDir, opts, prog, ctx)
+		} else {
+			output, err = comp.Decompress(selectDecompressor, srcFile, dstDir, opts, prog)
+		}
+		var mismatch *comp.ErrChecksumMismatch
+		if err != nil {
+			// непустые каталоги остаются, если файлы сохранены
+			removeDirs(created)
+		}
+		if err != nil && !errors.As(err, &mismatch) {
+			cmd.Println(color.RedString("File can't be uncompressed! Decompression failed."))
+			var unsafePath *comp.ErrUnsafePath
+			var unsafeLink *comp.ErrUnsafeLink
+			if errors.Is(err, fs.ErrPermission) {
+				cmd.Println("Use --no-owner or --no-perms to skip restoring file attributes.")
+			} else if errors.As(err, &unsafePath) || errors.As(err, &unsafeLink) {
+				cmd.Println("Use --allow-unsafe-paths only if you trust the archive.")
+			}
+			return err
+		}
+		if showProgress {
+			cmd.Println("files: ")
+			for _, out := range output {
+				relPath := strings.TrimPrefix(out.Path, filepath.Clean(dstDir))
+
+				if out.NewChecksum != out.OldChecksum {
+					cmd.Println(color.RedString(".%s: checksum mismatch", relPath))
+					continue
+				}
+				cmd.Println(color.GreenString(".%s", relPath))
+			}
+		}
+		if mismatch != nil {
+			cmd.Println(color.RedString("Decompression failed: checksum mismatch of %d files.", len(mismatch.Paths)))
+			switch opts.OnMismatch {
+			case comp.MismatchKeep:
+				cmd.Println("The mismatching files are kept.")
+			case comp.MismatchDelete:
+				cmd.Println("The mismatching files were removed.")
+			default:
+				cmd.Println("The extracted files and directories were removed.")
+			}
+			cmd.SilenceUsage = true
+			return &ExitCodeError{exitChecksumMismatch, err}
+		}
+
+		cmd.Println(color.GreenString("\nDecompression succeeded!"))
+		return nil
+	},
+}
+
+func init() {
+	uncompressCmd.Flags().StringVar(&decompDest, "dest", "", "output file or directory path")
+	uncompressCmd.Flags().BoolVarP(&decompQuiet, "quiet", "q", false, "quiet mode (no progress output)")
+	uncompressCmd.Flags().BoolVar(&decompNoPerms, "no-perms", false, "don't restore file permissions")
+	uncompressCmd.Flags().BoolVar(&decompNoOwner, "no-owner", false, "don't restore file owner and group")
+	uncompressCmd.Flags().BoolVar(&decompUnsafe, "allow-unsafe-paths", false,
+		"allow absolute paths, \"..\" and symlinks pointing outside the destination (trusted archives only)")
+	uncompressCmd.Flags().StringVar(&decompOnMismatch, "on-mismatch", "fail",
+		"what to do with files whose checksum doesn't match: fail (remove all files), keep or delete")
+	uncompressCmd.Flags().StringArrayVar(&decompExclude, "exclude", nil, "don't extract entries matching the path or pattern (repeatable)")
+}
+
+// decompressStdin распаковывает архив из stdin. Потоковый архив читается
+// по мере поступления, остальные сначала копируются во временный файл.
+func decompressStdin(
+	stdin *os.File, dstDir string, opts comp.DecompressOptions, prog *utiles.Progress[int64], ctx context.Context,
+) ([]*comp.DecompressedFile, error) {
+	r := bufio.NewReader(stdin)
+	if head, err := r.Peek(int(comp.HeaderSize)); err == nil {
+		header, err := comp.ReadHeader(bytes.NewReader(head))
+		if err == nil && header.Flags&comp.FlagStream != 0 {
+			return comp.DecompressStream(selectDecompressor, r, dstDir, opts, prog)
+		}
+	}
+
+	spool, err := os.CreateTemp("", "temp-comp-*.dedal-temp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	defer cleanup(spool.Name(), ctx, spool)()
+	if _, err := io.Copy(spool, r); err != nil {
+		return nil, err
+	}
+	return comp.Decompress(selectDecompressor, spool, dstDir, opts, prog)
+}
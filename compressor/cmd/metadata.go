@@ -1,65 +1,154 @@
-package cmd
-
-import (
-	comp "compressor/internal/compressing"
-	"sort"
-
-	"compressor/internal/utiles"
-	"fmt"
-	"os"
-
-	"github.com/fatih/color"
-	"github.com/spf13/cobra"
-)
-
-var metadataCmd = &cobra.Command{
-	Use:   "metadata <file>",
-	Short: "Print file metadata",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		defer func() {
-			if r := recover(); r != nil {
-				cobra.CompErrorln("Metadata not found")
-			}
-		}()
-
-		path := args[0]
-
-		file, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-
-		md, size, err := comp.ReadFooterMetadata(file)
-		if err != nil {
-			cmd.Println(color.RedString("File doesn't contain meatadata"))
-			return err
-		}
-		cmd.Printf("Size: %d bytes\n", size)
-
-		titles := []string{"File", "Size", "Checksum"}
-		rows := make([][]string, len(md.FileMap))
-		files := md.FileMap
-		for i := range rows {
-			rows[i] = []string{
-				files[i].Path,
-				fmt.Sprintf("%d bytes", files[i].Size),
-				files[i].Checksum,
-			}
-		}
-		sort.Slice(rows, func(i, j int) bool {
-			return rows[i][0] < rows[j][0]
-		})
-		cmd.Println()
-		tp := utiles.TableParams{
-			ColSep:      "   ",
-			RowSep:      "   ",
-			VerticalSep: false,
-			Writer:      cmd.OutOrStdout(),
-		}
-		utiles.ShowTable(titles, rows, tp)
-		cmd.Println()
-		return nil
-	},
-}
+package cmd
+
+import (
+	comp "compressor/internal/compressing"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"compressor/internal/utiles"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// entriesAt возвращает запись с путем path и записи внутри нее, если это
+// каталог. В архивах с индексом читаются только нужные записи.
+func entriesAt(file *os.File, path string) ([]comp.File, []comp.Codec, error) {
+	ix, err := comp.OpenIndex(file)
+	if errors.Is(err, comp.ErrNoIndex) {
+		md, _, err := comp.ReadFooterMetadata(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		path = filepath.Clean(path)
+		var files []comp.File
+		for _, f := range md.FileMap {
+			if f.Path == path || strings.HasPrefix(f.Path, path+string(filepath.Separator)) {
+				files = append(files, f)
+			}
+		}
+		return files, md.EntryCodecs(), nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	var files []comp.File
+	var noMatch *comp.ErrNoMatch
+	if f, err := ix.Lookup(path); err == nil {
+		files = append(files, *f)
+	} else if !errors.As(err, &noMatch) {
+		return nil, nil, err
+	}
+	inside, err := ix.List(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(files, inside...), ix.Codecs, nil
+}
+
+var metadataPath string
+var metadataCmd = &cobra.Command{
+	Use:   "metadata [flags] <file>",
+	Short: "Print file metadata",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defer func() {
+			if r := recover(); r != nil {
+				cobra.CompErrorln("Metadata not found")
+			}
+		}()
+
+		path := args[0]
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		var (
+			files  []comp.File
+			codecs []comp.Codec
+			size   int64
+		)
+		if metadataPath != "" {
+			files, codecs, err = entriesAt(file, metadataPath)
+			if err == nil && len(files) == 0 {
+				err = &comp.ErrNoMatch{Pattern: metadataPath}
+			}
+		} else {
+			var md *comp.Metadata
+			if md, size, err = comp.ReadFooterMetadata(file); err == nil {
+				files, codecs = md.FileMap, md.EntryCodecs()
+			}
+		}
+		if err != nil {
+			var noMatch *comp.ErrNoMatch
+			var notArchive *comp.ErrNotArchive
+			var version *comp.ErrUnsupportedVersion
+			if errors.As(err, &notArchive) || errors.As(err, &version) || errors.As(err, &noMatch) {
+				cmd.Println(color.RedString("%s: %v", path, err))
+			} else {
+				cmd.Println(color.RedString("File doesn't contain meatadata"))
+			}
+			return err
+		}
+		if header, err := comp.ReadHeader(file); err == nil {
+			cmd.Printf("Format version: %d\n", header.Version)
+		}
+		if metadataPath == "" {
+			cmd.Printf("Size: %d bytes\n", size)
+		}
+
+		// у файлов с разными моделями одного типа сжатия виден номер кодека
+		typeCount := make(map[string]int)
+		for _, c := range codecs {
+			typeCount[c.Type]++
+		}
+
+		titles := []string{"File", "Size", "Codec", "Checksum"}
+		rows := make([][]string, len(files))
+		for i := range rows {
+			name, codec := files[i].Path, "?"
+			switch {
+			case files[i].Kind != comp.KindFile:
+				codec = files[i].Kind.String()
+				if files[i].LinkTarget != "" {
+					name += " -> " + files[i].LinkTarget
+				}
+			case files[i].Codec >= 0 && files[i].Codec < len(codecs):
+				codec = codecs[files[i].Codec].Type
+				if typeCount[codec] > 1 {
+					codec = fmt.Sprintf("%s #%d", codec, files[i].Codec)
+				}
+			}
+			rows[i] = []string{
+				name,
+				fmt.Sprintf("%d bytes", files[i].Size),
+				codec,
+				files[i].Checksum,
+			}
+		}
+		sort.Slice(rows, func(i, j int) bool {
+			return rows[i][0] < rows[j][0]
+		})
+		cmd.Println()
+		tp := utiles.TableParams{
+			ColSep:      "   ",
+			RowSep:      "   ",
+			VerticalSep: false,
+			Writer:      cmd.OutOrStdout(),
+		}
+		utiles.ShowTable(titles, rows, tp)
+		cmd.Println()
+		return nil
+	},
+}
+
+func init() {
+	metadataCmd.Flags().StringVar(&metadataPath, "path", "", "print only the entry with the path (and the entries inside it)")
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"cmp"
+	comp "compressor/internal/compressing"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const modelSniffSize = 512
+
+// Режим моделей сжатия: одна общая модель (--solid, по умолчанию), своя
+// модель для каждого файла (--per-file) или по модели на группу похожих
+// файлов (--groups).
+var (
+	compSolid   bool
+	compPerFile bool
+	compGroups  int
+)
+
+func addModelFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&compSolid, "solid", false, "build one model for all files (default)")
+	cmd.Flags().BoolVar(&compPerFile, "per-file", false, "build a model for every file")
+	cmd.Flags().IntVar(&compGroups, "groups", 0, "build N models for files grouped by extension and content")
+	cmd.MarkFlagsMutuallyExclusive("solid", "per-file", "groups")
+}
+
+// sharedModel сообщает, строит ли тип сжатия модель по всем файлам,
+// например таблицу кодов Хаффмана. Остальным типам режим моделей не нужен.
+func sharedModel(compType string) bool { return compType == huffmanCompressionType }
+
+// modelCompressors создает компрессоры типа compType для файлов: по
+// одному на каждую группу файлов с общей моделью
+func modelCompressors(
+	compType string, pathes []string, compArgs map[string]any,
+) (map[string]comp.CompressionBase, error) {
+	groups := [][]string{pathes}
+	if sharedModel(compType) {
+		var err error
+		if groups, err = modelGroups(pathes); err != nil {
+			return nil, err
+		}
+	}
+
+	compressors := make(map[string]comp.CompressionBase, len(pathes))
+	for _, group := range groups {
+		size, err := totalSize(group)
+		if err != nil {
+			return nil, err
+		}
+		c, err := selectCompressor(compType, group, compArgs, size)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range group {
+			compressors[path] = c
+		}
+	}
+	return compressors, nil
+}
+
+// modelGroups делит файлы на группы с общей моделью
+func modelGroups(pathes []string) ([][]string, error) {
+	switch {
+	case compPerFile:
+		groups := make([][]string, len(pathes))
+		for i, path := range pathes {
+			groups[i] = []string{path}
+		}
+		return groups, nil
+	case compGroups < 0:
+		return nil, fmt.Errorf("number of groups must be positive: %d", compGroups)
+	case compGroups > 0:
+		return clusterFiles(pathes, compGroups)
+	default:
+		return [][]string{pathes}, nil
+	}
+}
+
+type fileCluster struct {
+	text   bool
+	size   int64
+	pathes []string
+}
+
+// clusterFiles группирует файлы по расширению, а файлы без расширения —
+// по типу содержимого. Пока групп больше n, самая маленькая группа
+// присоединяется к самой большой группе того же вида (текст или нет).
+func clusterFiles(pathes []string, n int) ([][]string, error) {
+	byKey := make(map[string]*fileCluster)
+	var clusters []*fileCluster
+	for _, path := range pathes {
+		sample, err := readSample(path, modelSniffSize)
+		if err != nil {
+			return nil, err
+		}
+		contentType := http.DetectContentType(sample)
+		key := strings.ToLower(filepath.Ext(path))
+		if key == "" {
+			key = contentType
+		}
+		size, err := totalSize([]string{path})
+		if err != nil {
+			return nil, err
+		}
+
+		c, ok := byKey[key]
+		if !ok {
+			c = &fileCluster{text: strings.HasPrefix(contentType, "text/")}
+			byKey[key] = c
+			clusters = append(clusters, c)
+		}
+		c.size += size
+		c.pathes = append(c.pathes, path)
+	}
+
+	bySize := func(a, b *fileCluster) int { return cmp.Compare(b.size, a.size) }
+	slices.SortStableFunc(clusters, bySize)
+	for len(clusters) > n {
+		last := clusters[len(clusters)-1]
+		clusters = clusters[:len(clusters)-1]
+		target := clusters[0]
+		for _, c := range clusters {
+			if c.text == last.text {
+				target = c
+				break
+			}
+		}
+		target.size += last.size
+		target.pathes = append(target.pathes, last.pathes...)
+		slices.SortStableFunc(clusters, bySize)
+	}
+
+	groups := make([][]string, len(clusters))
+	for i, c := range clusters {
+		groups[i] = c.pathes
+	}
+	return groups, nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	comp "compressor/internal/compressing"
+	"compressor/internal/store"
+	"compressor/internal/utiles"
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var rmQuiet bool
+var rmCmd = &cobra.Command{
+	Use:   "rm [flags] <file> <paths...>",
+	Short: "Remove entries from an archive",
+	Long: `Rebuild the archive without the entries matching the paths or glob
+patterns (as for uncompress --exclude). A directory is removed with its
+contents. The other files are copied without recompression.`,
+	Args:         cobra.MinimumNArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var removed []string
+		err := rewriteArchive(args[0], func(src, dst *os.File) (err error) {
+			removed, _, _, err = comp.RemoveEntries(selectDecompressor, src, args[1:], dst)
+			return err
+		})
+		if err != nil {
+			cmd.Println(color.RedString("Entries can't be removed."))
+			return err
+		}
+		if !rmQuiet {
+			for _, path := range removed {
+				cmd.Printf("removed %s\n", path)
+			}
+		}
+		cmd.Println(color.GreenString("%d entries removed!", len(removed)))
+		return nil
+	},
+}
+
+var updateQuiet bool
+var updateCmd = &cobra.Command{
+	Use:   "update [flags] <file> <files|directories...>",
+	Short: "Replace or add files in an archive",
+	Long: `Rebuild the archive with the files compressed anew. Entries with the
+same paths are replaced, other files are added; paths are named as by add.
+The other entries are copied without recompression.`,
+	Args:         cobra.MinimumNArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pathes, err := addPathes(args[1:])
+		if err != nil {
+			return err
+		}
+		size, err := totalSize(pathes)
+		if err != nil {
+			return err
+		}
+		fallback := store.NewCompressor()
+		comps, err := selectCompressors(pathes, compressionArgs(), fallback)
+		if err != nil {
+			return err
+		}
+
+		prog := utiles.NewProgress[int64](len(pathes))
+		if !updateQuiet {
+			prog.ShowProgress(size)
+			defer prog.Close()
+		} else {
+			prog.Close()
+		}
+
+		err = rewriteArchive(args[0], func(src, dst *os.File) error {
+			_, _, err := comp.UpdateFiles(selectDecompressor, comps, fallback, pathes, src, dst, prog)
+			return err
+		})
+		if err != nil {
+			cmd.Println(color.RedString("Files can't be updated."))
+			return err
+		}
+		if !updateQuiet {
+			cmd.Println()
+		}
+		cmd.Println(color.GreenString("Files updated!"))
+		return nil
+	},
+}
+
+func init() {
+	rmCmd.Flags().BoolVarP(&rmQuiet, "quiet", "q", false, "don't list removed entries")
+	addCompressionFlags(updateCmd)
+	updateCmd.Flags().BoolVarP(&updateQuiet, "quiet", "q", false, "quiet mode (no progress output)")
+}
+
+// rewriteArchive пишет новый архив во временный файл рядом с исходным и
+// переименовывает его поверх исходного только после успешной записи,
+// поэтому при ошибке или прерывании исходный архив не меняется.
+func rewriteArchive(path string, rewrite func(src, dst *os.File) error) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.CreateTemp(filepath.Dir(path), "temp-comp-*.dedal-temp")
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	defer cleanup(dst.Name(), ctx, dst)()
+
+	if err := rewrite(src, dst); err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := dst.Chmod(info.Mode().Perm()); err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := os.Rename(dst.Name(), path); err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+	return nil
+}
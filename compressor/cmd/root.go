@@ -1,25 +1,38 @@
-package cmd
-
-import (
-	"fmt"
-	"os"
-
-	"github.com/spf13/cobra"
-)
-
-func NewRootCommand() *cobra.Command {
-	rootCmd := &cobra.Command{
-		Use:   "compressor",
-		Short: "Compressor is a CLI tool for files or directory compressing and uncompressing ",
-	}
-	rootCmd.AddCommand(compressCmd, uncompressCmd, metadataCmd)
-	return rootCmd
-}
-
-func Execute(rootCmd *cobra.Command) error {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		return err
-	}
-	return nil
-}
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func NewRootCommand() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "compressor",
+		Short: "Compressor is a CLI tool for files or directory compressing and uncompressing ",
+	}
+	rootCmd.AddCommand(compressCmd, uncompressCmd, metadataCmd, catCmd, testCmd, addCmd, rmCmd, updateCmd)
+	return rootCmd
+}
+
+// exitChecksumMismatch is the exit status when decoded data doesn't
+// match the checksum stored in the archive.
+const exitChecksumMismatch = 2
+
+// ExitCodeError sets the exit status of the program.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string { return e.Err.Error() }
+func (e *ExitCodeError) Unwrap() error { return e.Err }
+
+func Execute(rootCmd *cobra.Command) error {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var testQuiet bool
+var testCmd = &cobra.Command{
+	Use:   "test [flags] <file>",
+	Short: "Verify archive checksums without extracting",
+	Long: `Decode every file of the archive without writing it and compare its
+checksum with the stored one. Exits with status 2 if any file fails.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		prog := utiles.NewProgress[int64](0)
+		if info, err := src.Stat(); !testQuiet && err == nil {
+			prog.ShowProgress(info.Size())
+		} else {
+			prog.Close()
+		}
+		output, err := comp.Verify(selectDecompressor, src, prog)
+		prog.Close()
+		if err != nil {
+			cmd.Println(color.RedString("Archive can't be tested."))
+			return err
+		}
+
+		var failed []string
+		for _, out := range output {
+			switch {
+			case out.Err != nil:
+				cmd.Println(color.RedString("FAIL %s: %v", out.Path, out.Err))
+			case out.NewChecksum != out.OldChecksum:
+				cmd.Println(color.RedString("FAIL %s: checksum mismatch", out.Path))
+			default:
+				if !testQuiet {
+					cmd.Println(color.GreenString("OK   %s", out.Path))
+				}
+				continue
+			}
+			failed = append(failed, out.Path)
+		}
+		if len(failed) > 0 {
+			cmd.SilenceUsage = true
+			err := fmt.Errorf("%d of %d files failed", len(failed), len(output))
+			return &ExitCodeError{exitChecksumMismatch, err}
+		}
+		cmd.Println(color.GreenString("\nAll %d files are OK", len(output)))
+		return nil
+	},
+}
+
+func init() {
+	testCmd.Flags().BoolVarP(&testQuiet, "quiet", "q", false, "print only failed files")
+}
@@ -1,56 +1,113 @@
-package cmd
-
-import (
-	"context"
-	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
-)
-
-// Context-aware temp directory cleanup
-func cleanup(pathToRemove string, ctx context.Context, filesToClose ...*os.File) {
-	select {
-	case <-ctx.Done():
-		for _, f := range filesToClose {
-			f.Close()
-		}
-		if pathToRemove == "" {
-			return
-		}
-		if err := os.RemoveAll(pathToRemove); err != nil {
-			fmt.Fprintf(os.Stderr, "Error while removing temp files: %v\n", err)
-		}
-	}
-}
-
-func getUniqueName(path string) string {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return path
-	}
-	ext := filepath.Ext(path)
-	name := strings.TrimSuffix(path, ext)
-	for i := 1; ; i++ {
-		newName := fmt.Sprintf("%s (%d)%s", name, i, ext)
-		if _, err := os.Stat(newName); os.IsNotExist(err) {
-			return newName
-		}
-	}
-}
-
-func totalSize(pathes []string) (int64, error) {
-	totalSize := int64(0)
-	for i := range pathes {
-		info, err := os.Stat(pathes[i])
-		if err != nil {
-			return 0, err
-		}
-		totalSize += info.Size()
-	}
-	return totalSize, nil
-}
-
-func isDir(path string) (bool, error) {
-	info, err := os.Stat(path)
-	return err == nil && info.IsDir(), err
-}
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Context-aware temp directory cleanup. The returned function must be
+// called before ctx is canceled on a normal return: it stops the cleanup
+// and waits for it if the work was already interrupted.
+func cleanup(pathToRemove string, ctx context.Context, filesToClose ...*os.File) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		select {
+		case <-ctx.Done():
+			for _, f := range filesToClose {
+				f.Close()
+			}
+			if pathToRemove == "" {
+				return
+			}
+			if err := os.RemoveAll(pathToRemove); err != nil {
+				fmt.Fprintf(os.Stderr, "Error while removing temp files: %v\n", err)
+			}
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// missingDirs возвращает еще не существующие каталоги пути dir, начиная
+// с самого глубокого
+func missingDirs(dir string) ([]string, error) {
+	var missing []string
+	for {
+		_, err := os.Stat(dir)
+		if err == nil {
+			return missing, nil
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		missing = append(missing, dir)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return missing, nil
+		}
+		dir = parent
+	}
+}
+
+// removeDirs удаляет каталоги dirs по порядку, если они пусты
+func removeDirs(dirs []string) {
+	for _, dir := range dirs {
+		os.Remove(dir)
+	}
+}
+
+func getUniqueName(path string) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	name := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		newName := fmt.Sprintf("%s (%d)%s", name, i, ext)
+		if _, err := os.Stat(newName); os.IsNotExist(err) {
+			return newName
+		}
+	}
+}
+
+// totalSize возвращает размер обычных файлов: только их содержимое сжимается
+func totalSize(pathes []string) (int64, error) {
+	totalSize := int64(0)
+	for i := range pathes {
+		info, err := os.Lstat(pathes[i])
+		if err != nil {
+			return 0, err
+		}
+		if info.Mode().IsRegular() {
+			totalSize += info.Size()
+		}
+	}
+	return totalSize, nil
+}
+
+// regularFiles отбирает обычные файлы без каталогов и символических ссылок
+func regularFiles(pathes []string) ([]string, error) {
+	var regular []string
+	for _, path := range pathes {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode().IsRegular() {
+			regular = append(regular, path)
+		}
+	}
+	return regular, nil
+}
+
+func isDir(path string) (bool, error) {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir(), err
+}
@@ -1,12 +1,17 @@
-package main
-
-import (
-	"compressor/compressor/cmd"
-	"os"
-)
-
-func main() {
-	if err := cmd.Execute(cmd.NewRootCommand()); err != nil {
-		os.Exit(1)
-	}
-}
+package main
+
+import (
+	"compressor/compressor/cmd"
+	"errors"
+	"os"
+)
+
+func main() {
+	if err := cmd.Execute(cmd.NewRootCommand()); err != nil {
+		var exit *cmd.ExitCodeError
+		if errors.As(err, &exit) {
+			os.Exit(exit.Code)
+		}
+		os.Exit(1)
+	}
+}
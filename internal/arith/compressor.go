@@ -0,0 +1,86 @@
+package arith
+
+import (
+	"bufio"
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+)
+
+const (
+	CompressionType = "ARITH"
+	MaxOrder        = 1
+)
+
+// Params is the footer body. The model adapts while coding,
+// so only its order is stored.
+type Params struct {
+	Order int
+}
+
+func (p *Params) EncodeBody(e *comp.Encoder) { e.Uvarint(uint64(p.Order)) }
+
+func (p *Params) DecodeBody(d *comp.Decoder) error {
+	p.Order = d.Int()
+	return d.Err()
+}
+
+// Compressor кодирует файлы интервальным кодером с адаптивной моделью
+// порядка 0 или 1. Модель начинает каждый файл заново.
+type Compressor struct {
+	Order int
+}
+
+func NewCompressor(order int) (*Compressor, error) {
+	if err := validateOrder(order); err != nil {
+		return nil, err
+	}
+	return &Compressor{order}, nil
+}
+
+func validateOrder(order int) error {
+	if order < 0 || order > MaxOrder {
+		return fmt.Errorf("model order must be in range [0, %d]: %d", MaxOrder, order)
+	}
+	return nil
+}
+
+func (c *Compressor) Preprocessing(_ []io.Reader) error { return nil }
+
+func (c *Compressor) CompressorData() (string, comp.Body) {
+	return CompressionType, &Params{c.Order}
+}
+
+func (c *Compressor) CompressFile(
+	src io.Reader, dst io.Writer, prog *utiles.Progress[int64],
+) (size int64, trailingBits uint8, err error) {
+	counter := &utiles.CountingWriter{W: dst}
+	w := bufio.NewWriter(counter)
+	r := bufio.NewReader(&utiles.ProgressReader{R: src, Prog: prog})
+
+	e := newEncoder(w)
+	m := newModel(c.Order)
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if err := m.encode(e, b); err != nil {
+			return 0, 0, err
+		}
+	}
+	if err := m.encodeEnd(e); err != nil {
+		return 0, 0, err
+	}
+	if err := e.flush(); err != nil {
+		return 0, 0, err
+	}
+	if err := w.Flush(); err != nil {
+		return 0, 0, err
+	}
+	return counter.N, 0, nil
+}
@@ -0,0 +1,107 @@
+package arith
+
+import (
+	"bytes"
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func compress(t *testing.T, data []byte, order int) ([]byte, comp.Body) {
+	t.Helper()
+	c, err := NewCompressor(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := utiles.NewProgress[int64](0)
+	prog.Close()
+	var payload bytes.Buffer
+	size, _, err := c.CompressFile(bytes.NewReader(data), &payload, prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(payload.Len()) {
+		t.Fatalf("got size %d, wrote %d bytes", size, payload.Len())
+	}
+	_, body := c.CompressorData()
+	return payload.Bytes(), body
+}
+
+func decompress(body comp.Body, payload []byte) ([]byte, error) {
+	d := NewDecompressor()
+	if err := d.Preprocessing(body, nil); err != nil {
+		return nil, err
+	}
+	prog := utiles.NewProgress[int64](0)
+	prog.Close()
+	var out bytes.Buffer
+	entry := comp.File{Size: int64(len(payload))}
+	input := &comp.DecompressionInput{Body: body, SourceFile: bytes.NewReader(payload), DestFile: &out, Entry: entry}
+	err := d.DecompressFile(input, prog)
+	return out.Bytes(), err
+}
+
+func testInputs() map[string][]byte {
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]byte, 20_000)
+	rnd.Read(random)
+	return map[string][]byte{
+		"empty":    nil,
+		"one byte": {0x42},
+		"same":     bytes.Repeat([]byte{0}, 10_000),
+		"text":     bytes.Repeat([]byte("the model of order one predicts the next byte\n"), 200),
+		"random":   random,
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for order := 0; order <= MaxOrder; order++ {
+		for name, data := range testInputs() {
+			t.Run(fmt.Sprintf("order %d %s", order, name), func(t *testing.T) {
+				payload, body := compress(t, data, order)
+				got, err := decompress(body, payload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Fatalf("got %d bytes, want %d: data differs", len(got), len(data))
+				}
+				// вероятности ограничены снизу, поэтому сжатие не бесконечное
+				if name == "same" && len(payload) > len(data)/20 {
+					t.Fatalf("%d bytes of one symbol take %d bytes", len(data), len(payload))
+				}
+			})
+		}
+	}
+}
+
+// Декодер читает ровно столько байт, сколько записал кодер, поэтому любой
+// оборванный поток — ошибка, а не зацикливание или паника
+func TestTruncated(t *testing.T) {
+	for order := 0; order <= MaxOrder; order++ {
+		for name, data := range testInputs() {
+			payload, body := compress(t, data, order)
+			for n := 0; n < len(payload); n += max(1, len(payload)/50) {
+				if _, err := decompress(body, payload[:n]); err == nil {
+					t.Fatalf("order %d %s: stream cut to %d of %d bytes was decoded", order, name, n, len(payload))
+				}
+			}
+			if _, err := decompress(body, payload[:len(payload)-1]); err == nil {
+				t.Fatalf("order %d %s: stream without the last byte was decoded", order, name)
+			}
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	for _, order := range []int{-1, MaxOrder + 1} {
+		if _, err := NewCompressor(order); err == nil {
+			t.Errorf("order %d was accepted", order)
+		}
+		if err := NewDecompressor().Preprocessing(&Params{order}, nil); err == nil {
+			t.Errorf("order %d was accepted by the decompressor", order)
+		}
+	}
+}
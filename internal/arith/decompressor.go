@@ -0,0 +1,53 @@
+package arith
+
+import (
+	"bufio"
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+)
+
+type Decompressor struct {
+	order int
+}
+
+func NewDecompressor() *Decompressor { return &Decompressor{} }
+
+func (d *Decompressor) FooterBodyType() comp.Body { return &Params{} }
+
+func (d *Decompressor) Preprocessing(data comp.Body, _ io.ReadSeeker) error {
+	params, ok := data.(*Params)
+	if !ok {
+		return fmt.Errorf("unexpected footer body %T", data)
+	}
+	if err := validateOrder(params.Order); err != nil {
+		return err
+	}
+	d.order = params.Order
+	return nil
+}
+
+func (d *Decompressor) DecompressFile(dd *comp.DecompressionInput, prog *utiles.Progress[int64]) error {
+	r := bufio.NewReader(&utiles.ProgressReader{R: dd.SourceFile, Prog: prog})
+	w := bufio.NewWriter(dd.DestFile)
+
+	dec, err := newDecoder(r)
+	if err != nil {
+		return err
+	}
+	m := newModel(d.order)
+	for {
+		b, ok, err := m.decode(dec)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if err := w.WriteByte(b); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
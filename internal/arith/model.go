@@ -0,0 +1,65 @@
+package arith
+
+// model — адаптивная модель байтов. Байт кодируется восемью битами по
+// двоичному дереву вероятностей; в модели порядка 1 для каждого значения
+// предыдущего байта используется отдельное дерево.
+type model struct {
+	more  uint16 // вероятность конца потока перед очередным байтом
+	trees [][256]uint16
+	prev  byte
+}
+
+func newModel(order int) *model {
+	m := &model{more: probInit, trees: make([][256]uint16, 1<<(8*order))}
+	for i := range m.trees {
+		for j := range m.trees[i] {
+			m.trees[i][j] = probInit
+		}
+	}
+	return m
+}
+
+func (m *model) tree() *[256]uint16 {
+	if len(m.trees) == 1 {
+		return &m.trees[0]
+	}
+	return &m.trees[m.prev]
+}
+
+func (m *model) encode(e *encoder, b byte) error {
+	if err := e.encodeBit(&m.more, 0); err != nil {
+		return err
+	}
+	tree := m.tree()
+	idx := 1
+	for i := 7; i >= 0; i-- {
+		bit := (b >> i) & 1
+		if err := e.encodeBit(&tree[idx], bit); err != nil {
+			return err
+		}
+		idx = idx<<1 | int(bit)
+	}
+	m.prev = b
+	return nil
+}
+
+func (m *model) encodeEnd(e *encoder) error { return e.encodeBit(&m.more, 1) }
+
+// decode возвращает очередной байт; ok = false в конце потока
+func (m *model) decode(d *decoder) (b byte, ok bool, err error) {
+	end, err := d.decodeBit(&m.more)
+	if err != nil || end == 1 {
+		return 0, false, err
+	}
+	tree := m.tree()
+	idx := 1
+	for idx < 256 {
+		bit, err := d.decodeBit(&tree[idx])
+		if err != nil {
+			return 0, false, err
+		}
+		idx = idx<<1 | int(bit)
+	}
+	m.prev = byte(idx)
+	return m.prev, true, nil
+}
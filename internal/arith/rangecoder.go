@@ -0,0 +1,123 @@
+package arith
+
+import "io"
+
+// Двоичный интервальный (range) кодер. Вероятность нуля хранится
+// в probBits битах и после каждого бита сдвигается к наблюдаемому значению.
+const (
+	probBits  = 11
+	probInit  = 1 << (probBits - 1)
+	moveBits  = 5
+	topValue  = 1 << 24
+	flushSize = 5
+)
+
+type encoder struct {
+	w         io.ByteWriter
+	low       uint64
+	rng       uint32
+	cache     byte
+	cacheSize int64
+}
+
+func newEncoder(w io.ByteWriter) *encoder {
+	return &encoder{w: w, rng: 0xFFFFFFFF, cacheSize: 1}
+}
+
+func (e *encoder) encodeBit(prob *uint16, bit uint8) error {
+	bound := (e.rng >> probBits) * uint32(*prob)
+	if bit == 0 {
+		e.rng = bound
+		*prob += (1<<probBits - *prob) >> moveBits
+	} else {
+		e.low += uint64(bound)
+		e.rng -= bound
+		*prob -= *prob >> moveBits
+	}
+	for e.rng < topValue {
+		e.rng <<= 8
+		if err := e.shiftLow(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shiftLow выводит старший байт low. Байты 0xFF откладываются, пока
+// не станет известно, будет ли в них перенос.
+func (e *encoder) shiftLow() error {
+	if uint32(e.low) < 0xFF000000 || e.low>>32 != 0 {
+		carry := byte(e.low >> 32)
+		temp := e.cache
+		for ; e.cacheSize > 0; e.cacheSize-- {
+			if err := e.w.WriteByte(temp + carry); err != nil {
+				return err
+			}
+			temp = 0xFF
+		}
+		e.cache = byte(e.low >> 24)
+	}
+	e.cacheSize++
+	e.low = (e.low & 0x00FFFFFF) << 8
+	return nil
+}
+
+func (e *encoder) flush() error {
+	for i := 0; i < flushSize; i++ {
+		if err := e.shiftLow(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type decoder struct {
+	r    io.ByteReader
+	rng  uint32
+	code uint32
+}
+
+func newDecoder(r io.ByteReader) (*decoder, error) {
+	d := &decoder{r: r, rng: 0xFFFFFFFF}
+	for i := 0; i < flushSize; i++ {
+		b, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		d.code = d.code<<8 | uint32(b)
+	}
+	return d, nil
+}
+
+// readByte читает очередной байт потока. Декодер читает ровно столько
+// байт, сколько записал кодер, поэтому конец потока — ошибка.
+func (d *decoder) readByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+func (d *decoder) decodeBit(prob *uint16) (uint8, error) {
+	var bit uint8
+	bound := (d.rng >> probBits) * uint32(*prob)
+	if d.code < bound {
+		d.rng = bound
+		*prob += (1<<probBits - *prob) >> moveBits
+	} else {
+		d.code -= bound
+		d.rng -= bound
+		*prob -= *prob >> moveBits
+		bit = 1
+	}
+	for d.rng < topValue {
+		b, err := d.readByte()
+		if err != nil {
+			return 0, err
+		}
+		d.rng <<= 8
+		d.code = d.code<<8 | uint32(b)
+	}
+	return bit, nil
+}
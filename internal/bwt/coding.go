@@ -0,0 +1,99 @@
+package bwt
+
+import "fmt"
+
+// Алфавит после RLE: серии нулей записываются цифрами runA и runB
+// в биективной двоичной системе, ненулевое значение MTF v — символом v+1
+const (
+	runA         = 0
+	runB         = 1
+	alphabetSize = 257
+)
+
+// moveToFront заменяет каждый байт его номером в списке недавно встреченных байт
+func moveToFront(data []byte) []byte {
+	var order [256]byte
+	for i := range order {
+		order[i] = byte(i)
+	}
+	out := make([]byte, len(data))
+	for i, b := range data {
+		j := 0
+		for order[j] != b {
+			j++
+		}
+		copy(order[1:j+1], order[:j])
+		order[0] = b
+		out[i] = byte(j)
+	}
+	return out
+}
+
+func moveToFrontInverse(indices []byte) []byte {
+	var order [256]byte
+	for i := range order {
+		order[i] = byte(i)
+	}
+	out := make([]byte, len(indices))
+	for i, j := range indices {
+		b := order[j]
+		copy(order[1:int(j)+1], order[:j])
+		order[0] = b
+		out[i] = b
+	}
+	return out
+}
+
+// encodeRuns кодирует серии нулей после MTF
+func encodeRuns(indices []byte) []uint16 {
+	symbols := make([]uint16, 0, len(indices)/2)
+	run := 0
+	flush := func() {
+		// run = sum (digit_i + 1) * 2^i, где digit_i — 0 для runA и 1 для runB
+		for run > 0 {
+			run--
+			symbols = append(symbols, uint16(run&1))
+			run >>= 1
+		}
+	}
+	for _, v := range indices {
+		if v == 0 {
+			run++
+			continue
+		}
+		flush()
+		symbols = append(symbols, uint16(v)+1)
+	}
+	flush()
+	return symbols
+}
+
+func decodeRuns(symbols []uint16, size int) ([]byte, error) {
+	indices := make([]byte, 0, size)
+	run, weight := 0, 1
+	for _, s := range symbols {
+		if s == runA || s == runB {
+			run += (int(s) + 1) * weight
+			weight <<= 1
+			if run > size-len(indices) {
+				return nil, fmt.Errorf("run of zeros exceeds block size")
+			}
+			continue
+		}
+		for ; run > 0; run-- {
+			indices = append(indices, 0)
+		}
+		weight = 1
+		if len(indices) == size {
+			return nil, fmt.Errorf("block is longer than its size")
+		}
+		indices = append(indices, byte(s-1))
+	}
+	for ; run > 0; run-- {
+		indices = append(indices, 0)
+	}
+	if len(indices) != size {
+		return nil, fmt.Errorf("block size mismatch: %d != %d", len(indices), size)
+	}
+	return indices, nil
+}
@@ -0,0 +1,76 @@
+package bwt
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestMoveToFront(t *testing.T) {
+	if got := moveToFront([]byte("aab")); !bytes.Equal(got, []byte{'a', 0, 'b'}) {
+		t.Fatalf("got %v", got)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	data := make([]byte, 5000)
+	for i := range data {
+		data[i] = byte(rnd.ExpFloat64() * 10)
+	}
+	for _, d := range [][]byte{nil, data, make([]byte, 100), bytes.Repeat([]byte{255, 0}, 50)} {
+		if got := moveToFrontInverse(moveToFront(d)); !bytes.Equal(got, d) {
+			t.Fatalf("got %v, want %v", got, d)
+		}
+	}
+}
+
+func TestRuns(t *testing.T) {
+	// длина серии в биективной двоичной системе, младшая цифра первой
+	encoded := map[int][]uint16{
+		1: {runA},
+		2: {runB},
+		3: {runA, runA},
+		4: {runB, runA},
+		5: {runA, runB},
+		6: {runB, runB},
+		7: {runA, runA, runA},
+	}
+	for run, want := range encoded {
+		if got := encodeRuns(make([]byte, run)); !slices.Equal(got, want) {
+			t.Errorf("run %d: got %v, want %v", run, got, want)
+		}
+	}
+
+	for run := 0; run <= 70; run++ {
+		// серия в начале, между ненулевыми значениями и в конце
+		indices := append(append(append(make([]byte, run), 5), make([]byte, run)...), 255)
+		indices = append(indices, make([]byte, run)...)
+		symbols := encodeRuns(indices)
+		if slices.ContainsFunc(symbols, func(s uint16) bool { return s >= alphabetSize }) {
+			t.Fatalf("symbol out of the alphabet in %v", symbols)
+		}
+		got, err := decodeRuns(symbols, len(indices))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, indices) {
+			t.Fatalf("run %d: got %v, want %v", run, got, indices)
+		}
+	}
+}
+
+func TestDecodeRunsErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		symbols []uint16
+		size    int
+	}{
+		{"run too long", []uint16{runB, runB}, 5},
+		{"too many values", []uint16{2, 3, 4}, 2},
+		{"too short", []uint16{runA, 2}, 3},
+	}
+	for _, c := range cases {
+		if got, err := decodeRuns(c.symbols, c.size); err == nil {
+			t.Errorf("%s: got %v, want an error", c.name, got)
+		}
+	}
+}
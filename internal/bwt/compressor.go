@@ -0,0 +1,97 @@
+package bwt
+
+import (
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+)
+
+const (
+	CompressionType = "BWT"
+
+	DefaultBlockSize = 900_000
+	MinBlockSize     = 1 << 10
+	MaxBlockSize     = 16 << 20
+)
+
+// Params is the footer body.
+type Params struct {
+	BlockSize int
+}
+
+func (p *Params) EncodeBody(e *comp.Encoder) { e.Uvarint(uint64(p.BlockSize)) }
+
+func (p *Params) DecodeBody(d *comp.Decoder) error {
+	p.BlockSize = d.Int()
+	return d.Err()
+}
+
+// Compressor сжимает файлы по блокам: преобразование Барроуза-Уилера,
+// move-to-front, кодирование серий нулей и коды Хаффмана для каждого блока.
+//
+// Блок записывается как флаг 1, размер блока, номер исходной строки,
+// число символов, длины кодов и сами коды; конец файла — флаг 0.
+type Compressor struct {
+	BlockSize int
+}
+
+func NewCompressor(blockSize int) (*Compressor, error) {
+	if err := validateBlockSize(blockSize); err != nil {
+		return nil, err
+	}
+	return &Compressor{blockSize}, nil
+}
+
+func validateBlockSize(blockSize int) error {
+	if blockSize < MinBlockSize || blockSize > MaxBlockSize {
+		return fmt.Errorf("block size must be in range [%d, %d]: %d", MinBlockSize, MaxBlockSize, blockSize)
+	}
+	return nil
+}
+
+func (c *Compressor) Preprocessing(_ []io.Reader) error { return nil }
+
+func (c *Compressor) CompressorData() (string, comp.Body) {
+	return CompressionType, &Params{c.BlockSize}
+}
+
+func (c *Compressor) CompressFile(
+	src io.Reader, dst io.Writer, prog *utiles.Progress[int64],
+) (size int64, trailingBits uint8, err error) {
+	bw := utiles.NewBitWriter(dst)
+	buf := make([]byte, c.BlockSize)
+	for {
+		n, err := io.ReadFull(src, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, 0, err
+		}
+		if err := writeBlock(bw, buf[:n]); err != nil {
+			return 0, 0, err
+		}
+		prog.Write(int64(n))
+	}
+	if err := bw.WriteBits(0, 1); err != nil {
+		return 0, 0, err
+	}
+	return bw.Flush()
+}
+
+func writeBlock(bw *utiles.BitWriter, block []byte) error {
+	last, primary := transform(block)
+	symbols := encodeRuns(moveToFront(last))
+
+	if err := bw.WriteBits(1, 1); err != nil {
+		return err
+	}
+	if err := bw.WriteBits(uint64(len(block)), blockSizeBits); err != nil {
+		return err
+	}
+	if err := bw.WriteBits(uint64(primary), blockSizeBits); err != nil {
+		return err
+	}
+	return writeSymbols(bw, symbols)
+}
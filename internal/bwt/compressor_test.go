@@ -0,0 +1,71 @@
+package bwt
+
+import (
+	"bytes"
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func roundTrip(t *testing.T, data []byte, blockSize int) {
+	t.Helper()
+	c, err := NewCompressor(blockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := utiles.NewProgress[int64](0)
+	prog.Close()
+
+	var payload bytes.Buffer
+	size, trailingBits, err := c.CompressFile(bytes.NewReader(data), &payload, prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, body := c.CompressorData()
+	d := NewDecompressor()
+	if err := d.Preprocessing(body, nil); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	entry := comp.File{Size: size, TrailingBits: trailingBits}
+	input := &comp.DecompressionInput{Body: body, SourceFile: bytes.NewReader(payload.Bytes()), DestFile: &out, Entry: entry}
+	if err := d.DecompressFile(input, prog); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("got %d bytes, want %d: data differs", out.Len(), len(data))
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]byte, 4*MinBlockSize)
+	rnd.Read(random)
+	text := bytes.Repeat([]byte("burrows wheeler transform groups similar contexts\n"), 4*MinBlockSize/50+1)
+	contents := map[string][]byte{
+		"text":     text,
+		"zeros":    make([]byte, 4*MinBlockSize),
+		"periodic": bytes.Repeat([]byte("ab"), 2*MinBlockSize),
+		"random":   random,
+	}
+	// размеры на границах блоков
+	sizes := []int{0, 1, MinBlockSize - 1, MinBlockSize, MinBlockSize + 1, 2 * MinBlockSize, 3*MinBlockSize + 17}
+	for name, data := range contents {
+		for _, size := range sizes {
+			t.Run(fmt.Sprintf("%s %d", name, size), func(t *testing.T) {
+				roundTrip(t, data[:size], MinBlockSize)
+			})
+		}
+	}
+	t.Run("default block", func(t *testing.T) { roundTrip(t, text, DefaultBlockSize) })
+}
+
+func TestBlockSize(t *testing.T) {
+	for _, size := range []int{0, MinBlockSize - 1, MaxBlockSize + 1} {
+		if _, err := NewCompressor(size); err == nil {
+			t.Errorf("block size %d was accepted", size)
+		}
+	}
+}
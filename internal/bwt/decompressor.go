@@ -0,0 +1,76 @@
+package bwt
+
+import (
+	"bufio"
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+)
+
+type Decompressor struct {
+	blockSize int
+}
+
+func NewDecompressor() *Decompressor { return &Decompressor{} }
+
+func (d *Decompressor) FooterBodyType() comp.Body { return &Params{} }
+
+func (d *Decompressor) Preprocessing(data comp.Body, _ io.ReadSeeker) error {
+	params, ok := data.(*Params)
+	if !ok {
+		return fmt.Errorf("unexpected footer body %T", data)
+	}
+	if err := validateBlockSize(params.BlockSize); err != nil {
+		return err
+	}
+	d.blockSize = params.BlockSize
+	return nil
+}
+
+func (d *Decompressor) DecompressFile(dd *comp.DecompressionInput, prog *utiles.Progress[int64]) error {
+	src := &utiles.ProgressReader{R: dd.SourceFile, Prog: prog}
+	br := utiles.NewBitReader(src, dd.Entry.BitSize())
+	dst := bufio.NewWriter(dd.DestFile)
+	for {
+		more, err := br.ReadBit()
+		if err != nil {
+			return err
+		}
+		if more == 0 {
+			break
+		}
+		block, err := d.readBlock(br)
+		if err != nil {
+			return err
+		}
+		if _, err := dst.Write(block); err != nil {
+			return err
+		}
+	}
+	return dst.Flush()
+}
+
+func (d *Decompressor) readBlock(br *utiles.BitReader) ([]byte, error) {
+	size, err := br.ReadBits(blockSizeBits)
+	if err != nil {
+		return nil, err
+	}
+	primary, err := br.ReadBits(blockSizeBits)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 || int(size) > d.blockSize || primary >= size {
+		return nil, fmt.Errorf("malformed block header")
+	}
+
+	symbols, err := readSymbols(br, int(size))
+	if err != nil {
+		return nil, err
+	}
+	indices, err := decodeRuns(symbols, int(size))
+	if err != nil {
+		return nil, err
+	}
+	return inverse(moveToFrontInverse(indices), int(primary)), nil
+}
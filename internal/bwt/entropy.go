@@ -0,0 +1,121 @@
+package bwt
+
+import (
+	alg "compressor/internal/huffman/algorithm"
+	"compressor/internal/utiles"
+	"fmt"
+)
+
+const (
+	maxCodeLen    = 20
+	codeLenBits   = 5
+	blockSizeBits = 32
+)
+
+func symbolKey(s uint16) string { return string([]byte{byte(s >> 8), byte(s)}) }
+
+func keySymbol(key string) uint16 { return uint16(key[0])<<8 | uint16(key[1]) }
+
+// writeSymbols кодирует символы блока каноническими кодами Хаффмана.
+// Перед кодами записываются их длины для всего алфавита (0 — символ не встречается).
+func writeSymbols(bw *utiles.BitWriter, symbols []uint16) error {
+	freqs := make(map[string]uint64)
+	for _, s := range symbols {
+		freqs[symbolKey(s)]++
+	}
+	huff := alg.NewHuffmanTree(2)
+	if err := huff.BuildTree(freqs); err != nil {
+		return err
+	}
+	lengths, err := huff.LimitedCodeLengths(maxCodeLen)
+	if err != nil {
+		return err
+	}
+	codes := alg.CanonicalCodes(lengths)
+
+	if err := bw.WriteBits(uint64(len(symbols)), blockSizeBits); err != nil {
+		return err
+	}
+	for s := uint16(0); s < alphabetSize; s++ {
+		if err := bw.WriteBits(uint64(lengths[symbolKey(s)]), codeLenBits); err != nil {
+			return err
+		}
+	}
+	for _, s := range symbols {
+		code := codes[symbolKey(s)]
+		if err := bw.WriteBits(code.Bits, code.Len); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canonicalDecoder декодирует канонические коды по длинам: коды одной
+// длины идут подряд, поэтому достаточно знать первый код каждой длины
+type canonicalDecoder struct {
+	first   [maxCodeLen + 1]uint64 // первый код длины
+	index   [maxCodeLen + 1]int    // номер его символа в symbols
+	count   [maxCodeLen + 1]int
+	symbols []uint16
+}
+
+func readSymbols(br *utiles.BitReader, maxSymbols int) ([]uint16, error) {
+	n, err := br.ReadBits(blockSizeBits)
+	if err != nil {
+		return nil, err
+	}
+	if int(n) > maxSymbols {
+		return nil, fmt.Errorf("block has too many symbols: %d", n)
+	}
+
+	lengths := make(map[string]uint8)
+	for s := uint16(0); s < alphabetSize; s++ {
+		length, err := br.ReadBits(codeLenBits)
+		if err != nil {
+			return nil, err
+		}
+		if length > maxCodeLen {
+			return nil, fmt.Errorf("invalid code length: %d", length)
+		}
+		if length != 0 {
+			lengths[symbolKey(s)] = uint8(length)
+		}
+	}
+	if err := alg.ValidateLengths(lengths); err != nil {
+		return nil, err
+	}
+
+	var d canonicalDecoder
+	codes := alg.CanonicalCodes(lengths)
+	for i, key := range alg.CanonicalOrder(lengths) {
+		code := codes[key]
+		if d.count[code.Len] == 0 {
+			d.first[code.Len], d.index[code.Len] = code.Bits, i
+		}
+		d.count[code.Len]++
+		d.symbols = append(d.symbols, keySymbol(key))
+	}
+
+	symbols := make([]uint16, n)
+	for i := range symbols {
+		if symbols[i], err = d.next(br); err != nil {
+			return nil, err
+		}
+	}
+	return symbols, nil
+}
+
+func (d *canonicalDecoder) next(br *utiles.BitReader) (uint16, error) {
+	var code uint64
+	for length := 1; length <= maxCodeLen; length++ {
+		bit, err := br.ReadBit()
+		if err != nil {
+			return 0, err
+		}
+		code = code<<1 | uint64(bit)
+		if d.count[length] > 0 && code >= d.first[length] && code-d.first[length] < uint64(d.count[length]) {
+			return d.symbols[d.index[length]+int(code-d.first[length])], nil
+		}
+	}
+	return 0, fmt.Errorf("invalid Huffman code")
+}
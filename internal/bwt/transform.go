@@ -0,0 +1,108 @@
+package bwt
+
+// sortRotations возвращает циклические сдвиги data в лексикографическом
+// порядке. Сдвиги сортируются удвоением длины сравниваемого префикса,
+// на каждом шаге сортировкой подсчетом по классам эквивалентности.
+func sortRotations(data []byte) []int32 {
+	n := len(data)
+	p := make([]int32, n)
+	c := make([]int32, n)
+	cnt := make([]int32, max(256, n))
+
+	for _, b := range data {
+		cnt[b]++
+	}
+	for i := 1; i < 256; i++ {
+		cnt[i] += cnt[i-1]
+	}
+	for i := n - 1; i >= 0; i-- {
+		cnt[data[i]]--
+		p[cnt[data[i]]] = int32(i)
+	}
+	classes := int32(1)
+	for i := 1; i < n; i++ {
+		if data[p[i]] != data[p[i-1]] {
+			classes++
+		}
+		c[p[i]] = classes - 1
+	}
+
+	pn := make([]int32, n)
+	cn := make([]int32, n)
+	for h := 1; h < n && int(classes) < n; h <<= 1 {
+		// сдвиги уже упорядочены по второй половине, сортируем по первой
+		for i := range p {
+			pn[i] = p[i] - int32(h)
+			if pn[i] < 0 {
+				pn[i] += int32(n)
+			}
+		}
+		clear(cnt[:classes])
+		for i := range pn {
+			cnt[c[pn[i]]]++
+		}
+		for i := int32(1); i < classes; i++ {
+			cnt[i] += cnt[i-1]
+		}
+		for i := n - 1; i >= 0; i-- {
+			cnt[c[pn[i]]]--
+			p[cnt[c[pn[i]]]] = pn[i]
+		}
+
+		cn[p[0]] = 0
+		classes = 1
+		for i := 1; i < n; i++ {
+			cur, prev := p[i], p[i-1]
+			curNext, prevNext := (int(cur)+h)%n, (int(prev)+h)%n
+			if c[cur] != c[prev] || c[curNext] != c[prevNext] {
+				classes++
+			}
+			cn[cur] = classes - 1
+		}
+		c, cn = cn, c
+	}
+	return p
+}
+
+// transform возвращает преобразование Барроуза-Уилера блока и номер
+// исходной строки среди отсортированных сдвигов
+func transform(data []byte) (last []byte, primary int) {
+	n := len(data)
+	last = make([]byte, n)
+	for i, rot := range sortRotations(data) {
+		if rot == 0 {
+			primary = i
+			last[i] = data[n-1]
+		} else {
+			last[i] = data[rot-1]
+		}
+	}
+	return last, primary
+}
+
+// inverse восстанавливает блок по последнему столбцу и номеру исходной строки
+func inverse(last []byte, primary int) []byte {
+	n := len(last)
+	var cnt [256]int
+	for _, b := range last {
+		cnt[b]++
+	}
+	var start [256]int
+	for i := 1; i < 256; i++ {
+		start[i] = start[i-1] + cnt[i-1]
+	}
+	// next[i] — строка, в которой стоит сдвиг, следующий за сдвигом строки i
+	next := make([]int32, n)
+	for i, b := range last {
+		next[start[b]] = int32(i)
+		start[b]++
+	}
+
+	data := make([]byte, n)
+	row := next[primary]
+	for i := range data {
+		data[i] = last[row]
+		row = next[row]
+	}
+	return data
+}
@@ -0,0 +1,78 @@
+package bwt
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// naiveRotations сортирует сдвиги сравнением строк целиком
+func naiveRotations(data []byte) []int32 {
+	rots := make([]int32, len(data))
+	for i := range rots {
+		rots[i] = int32(i)
+	}
+	slices.SortStableFunc(rots, func(a, b int32) int {
+		return bytes.Compare(rotation(data, a), rotation(data, b))
+	})
+	return rots
+}
+
+func rotation(data []byte, i int32) []byte {
+	return append(slices.Clone(data[i:]), data[:i]...)
+}
+
+func transformInputs() map[string][]byte {
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]byte, 3000)
+	rnd.Read(random)
+	small := make([]byte, 3000)
+	for i := range small {
+		small[i] = "ab"[rnd.Intn(2)]
+	}
+	return map[string][]byte{
+		"one byte":        {'x'},
+		"banana":          []byte("banana"),
+		"periodic":        bytes.Repeat([]byte("ab"), 500),
+		"periodic odd":    bytes.Repeat([]byte("abc"), 333),
+		"zeros":           make([]byte, 1000),
+		"almost periodic": append(bytes.Repeat([]byte("ab"), 500), 'a'),
+		"two letters":     small,
+		"random":          random,
+	}
+}
+
+func TestSortRotations(t *testing.T) {
+	if got := sortRotations(nil); len(got) != 0 {
+		t.Fatalf("got %v for empty data", got)
+	}
+	for name, data := range transformInputs() {
+		t.Run(name, func(t *testing.T) {
+			got, want := sortRotations(data), naiveRotations(data)
+			// равные сдвиги периодичных данных могут стоять в любом порядке,
+			// поэтому сравниваются сами сдвиги
+			seen := make([]bool, len(data))
+			for i := range got {
+				if seen[got[i]] {
+					t.Fatalf("rotation %d is repeated", got[i])
+				}
+				seen[got[i]] = true
+				if !bytes.Equal(rotation(data, got[i]), rotation(data, want[i])) {
+					t.Fatalf("row %d: got rotation %d, want %d", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestInverse(t *testing.T) {
+	for name, data := range transformInputs() {
+		t.Run(name, func(t *testing.T) {
+			last, primary := transform(data)
+			if got := inverse(last, primary); !bytes.Equal(got, data) {
+				t.Fatalf("got %q, want %q", got, data)
+			}
+		})
+	}
+}
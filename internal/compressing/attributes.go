@@ -0,0 +1,66 @@
+package compressing
+
+import (
+	"compressor/internal/utiles"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+// MismatchPolicy says what Decompress does when checksums don't match.
+type MismatchPolicy int
+
+const (
+	MismatchFail   MismatchPolicy = iota // remove all extracted files
+	MismatchKeep                         // keep the files as decoded
+	MismatchDelete                       // remove only the mismatching files
+)
+
+// DecompressOptions control what is restored on extraction.
+type DecompressOptions struct {
+	NoPerms bool // keep default permissions instead of the archived mode
+	NoOwner bool // don't restore uid and gid
+
+	// AllowUnsafePaths disables the checks of entry paths and symlink
+	// targets, for trusted archives only
+	AllowUnsafePaths bool
+
+	// Include and Exclude select the entries to extract by path or glob
+	// pattern, see utiles.MatchGlob. A pattern without '/' matches names
+	// at any depth, a pattern matching a directory selects everything
+	// in it. An empty Include selects all entries.
+	Include, Exclude []string
+
+	OnMismatch MismatchPolicy
+}
+
+// restoreAttributes восстанавливает владельца, режим и время файла.
+// Владелец меняется первым, потому что chown сбрасывает биты setuid и setgid.
+// У символических ссылок восстанавливается только владелец.
+func restoreAttributes(path string, entry *File, opts DecompressOptions) error {
+	if entry.HasOwner && !opts.NoOwner && runtime.GOOS != "windows" {
+		if err := os.Lchown(path, entry.Uid, entry.Gid); err != nil {
+			return fmt.Errorf("can't restore owner of %s: %w", entry.Path, err)
+		}
+	}
+	if entry.Kind == KindSymlink {
+		return nil
+	}
+	if entry.Mode != 0 && !opts.NoPerms {
+		if err := os.Chmod(path, utiles.FileMode(entry.Mode)); err != nil {
+			return fmt.Errorf("can't restore mode of %s: %w", entry.Path, err)
+		}
+	}
+	if entry.ModTime != 0 {
+		mtime := time.Unix(0, entry.ModTime)
+		atime := mtime
+		if entry.AccessTime != 0 {
+			atime = time.Unix(0, entry.AccessTime)
+		}
+		if err := os.Chtimes(path, atime, mtime); err != nil {
+			return fmt.Errorf("can't restore times of %s: %w", entry.Path, err)
+		}
+	}
+	return nil
+}
@@ -1,189 +1,467 @@
-package compressing
-
-import (
-	"bufio"
-	"compressor/internal/utiles"
-	"encoding/binary"
-	"fmt"
-	"io"
-	"os"
-
-	"golang.org/x/sync/errgroup"
-)
-
-type ErrCompression struct{ Cause error }
-
-func (e *ErrCompression) Error() string { return fmt.Sprintf("Compression failed: %v", e.Cause) }
-func (e *ErrCompression) Unwrap() error { return e.Cause }
-
-type CompressionBase interface {
-	CompressorData() (name string, data Body)
-	CompressFile(src io.Reader, dst io.Writer, prog *utiles.Progress[int64]) (size int64, err error)
-}
-
-type SimpleCompressor interface {
-	Preprocessing(srcs []io.Reader) error
-	CompressionBase
-}
-
-type FastCompressor interface {
-	Preprocessing(srcs []io.Reader) (sizes []int64, err error)
-	CompressionBase
-}
-
-// CompressFiles compresses the given files using the specified compressor.
-func CompressFiles(
-	c CompressionBase, pathes []string, dst *os.File, prog *utiles.Progress[int64],
-) (contentSize int64, footerSize int64, err error) {
-	srcs, err := utiles.OpenFiles(pathes...)
-	if err != nil {
-		return 0, 0, err
-	}
-	defer utiles.CloseFiles(srcs)
-
-	var fileMap []File
-	switch comp := c.(type) {
-	case FastCompressor:
-		if fileMap, err = fastCompress(comp, srcs, dst, prog); err != nil {
-			return 0, 0, &ErrCompression{err}
-		}
-	case SimpleCompressor:
-		if fileMap, err = simpleCompress(comp, srcs, dst, prog); err != nil {
-			return 0, 0, &ErrCompression{err}
-		}
-	default:
-		return 0, 0, fmt.Errorf("unsupported compressor type")
-	}
-
-	if err := formatPathes(fileMap); err != nil {
-		return 0, 0, &ErrCompression{err}
-	}
-	name, data := c.CompressorData()
-	footer := newFooter(name, fileMap, data)
-
-	footerSize, err = writeFooter(footer, dst)
-	if err != nil {
-		return 0, 0, &ErrCompression{err}
-	}
-
-	// Write footer size at the end of the file
-	if err = binary.Write(dst, binary.LittleEndian, footerSize); err != nil {
-		return 0, 0, &ErrCompression{fmt.Errorf("error while writing footer size: %v", err)}
-	}
-
-	lastFile := fileMap[len(fileMap)-1]
-	contentSize = lastFile.Offset + lastFile.Size
-	return contentSize, footerSize, nil
-}
-
-// compress handles compression for SimpleCompressor implementations.
-func simpleCompress(
-	c SimpleCompressor, srcs []*os.File, dst *os.File, prog *utiles.Progress[int64],
-) ([]File, error) {
-	bufReaders := make([]*bufio.Reader, len(srcs))
-	readers := make([]io.Reader, len(srcs))
-
-	for i, f := range srcs {
-		bufReaders[i] = bufio.NewReader(f)
-		readers[i] = bufReaders[i]
-	}
-
-	if err := c.Preprocessing(readers); err != nil {
-		return nil, &ErrCompression{err}
-	}
-
-	var (
-		offset  int64
-		fileMap = make([]File, len(srcs))
-	)
-
-	for i, f := range srcs {
-		if _, err := f.Seek(0, io.SeekStart); err != nil {
-			return nil, err
-		}
-		bufReaders[i].Reset(f)
-
-		size, err := c.CompressFile(bufReaders[i], dst, prog)
-		if err != nil {
-			return nil, &ErrCompression{err}
-		}
-
-		if _, err := f.Seek(0, io.SeekStart); err != nil {
-			return nil, &ErrCompression{err}
-		}
-		checksum, _ := checksum(f)
-
-		fileMap[i] = File{
-			Path:     f.Name(),
-			Checksum: checksum,
-			Offset:   offset,
-			Size:     size,
-		}
-		offset += size
-	}
-	return fileMap, nil
-}
-
-// fastCompress handles compression for FastCompressor implementations
-// with concurrent writes.
-func fastCompress(
-	c FastCompressor, srcs []*os.File, dst *os.File, prog *utiles.Progress[int64],
-) ([]File, error) {
-	bufReaders := make([]*bufio.Reader, len(srcs))
-	readers := make([]io.Reader, len(srcs))
-
-	for i, f := range srcs {
-		bufReaders[i] = bufio.NewReader(f)
-		readers[i] = bufReaders[i]
-	}
-
-	sizes, err := c.Preprocessing(readers)
-	if err != nil {
-		return nil, err
-	}
-
-	var (
-		offset  int64
-		fileMap = make([]File, len(srcs))
-	)
-
-	for i, f := range srcs {
-		if _, err := f.Seek(0, io.SeekStart); err != nil {
-			return nil, err
-		}
-		checksum, _ := checksum(f)
-
-		fileMap[i] = File{
-			Path:     f.Name(),
-			Checksum: checksum,
-			Offset:   offset,
-			Size:     sizes[i],
-		}
-		offset += sizes[i]
-	}
-
-	if err := dst.Truncate(offset); err != nil {
-		return nil, err
-	}
-
-	var eg errgroup.Group
-	for i, f := range srcs {
-		if _, err := f.Seek(0, io.SeekStart); err != nil {
-			return nil, err
-		}
-		bufReaders[i].Reset(f)
-		writer := io.NewOffsetWriter(dst, fileMap[i].Offset)
-		eg.Go(func() error {
-			_, err := c.CompressFile(bufReaders[i], writer, prog)
-			return err
-		})
-	}
-	if err := eg.Wait(); err != nil {
-		return nil, err
-	}
-
-	if _, err := dst.Seek(0, io.SeekEnd); err != nil {
-		return nil, err
-	}
-	return fileMap, nil
-}
+package compressing
+
+import (
+	"bufio"
+	"bytes"
+	"compressor/internal/utiles"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"golang.org/x/sync/errgroup"
+)
+
+type ErrCompression struct{ Cause error }
+
+func (e *ErrCompression) Error() string { return fmt.Sprintf("Compression failed: %v", e.Cause) }
+func (e *ErrCompression) Unwrap() error { return e.Cause }
+
+type CompressionBase interface {
+	CompressorData() (name string, data Body)
+	CompressFile(
+		src io.Reader, dst io.Writer, prog *utiles.Progress[int64],
+	) (size int64, trailingBits uint8, err error)
+}
+
+// Versioned is implemented by compressors whose footer body format
+// has changed over time. The version is stored in Metadata.
+type Versioned interface {
+	FormatVersion() int
+}
+
+type SimpleCompressor interface {
+	Preprocessing(srcs []io.Reader) error
+	CompressionBase
+}
+
+type FastCompressor interface {
+	Preprocessing(srcs []io.Reader) (sizes []int64, err error)
+	CompressionBase
+}
+
+// CompressFiles compresses the given files using the specified compressor.
+func CompressFiles(
+	c CompressionBase, pathes []string, dst *os.File, prog *utiles.Progress[int64],
+) (contentSize int64, footerSize int64, err error) {
+	comps := make([]CompressionBase, len(pathes))
+	for i := range comps {
+		comps[i] = c
+	}
+	return CompressFilesWith(comps, nil, pathes, dst, prog)
+}
+
+// CompressFilesWith compresses every file with its own compressor: comps[i]
+// is used for pathes[i]. Files sharing a compressor are preprocessed
+// together and share its footer body. Directories, symlinks and repeated
+// hard links are stored without payload, their compressors are ignored.
+//
+// If fallback is not nil, files for which a FastCompressor estimates no
+// gain are compressed with fallback instead.
+//
+// contentSize is the size of the header and payloads.
+func CompressFilesWith(
+	comps []CompressionBase, fallback FastCompressor,
+	pathes []string, dst *os.File, prog *utiles.Progress[int64],
+) (contentSize int64, footerSize int64, err error) {
+	if len(comps) != len(pathes) {
+		return 0, 0, fmt.Errorf("got %d compressors for %d files", len(comps), len(pathes))
+	}
+	fileMap, err := newEntries(pathes)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := writeHeader(newHeader(0), dst); err != nil {
+		return 0, 0, &ErrCompression{err}
+	}
+	codecs, bodies, contentSize, err := compressEntries(comps, fallback, pathes, fileMap, dst, HeaderSize, 0, prog)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	footerSize, err = finishArchive(newFooter(codecs, fileMap, bodies), dst, contentSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	return contentSize, footerSize, nil
+}
+
+// newEntries создает записи архива для файлов. Пути записей считаются
+// от общего каталога файлов, повторные жесткие ссылки указывают на первую.
+func newEntries(pathes []string) ([]File, error) {
+	// атрибуты читаются до сжатия, пока чтение не изменило время доступа
+	fileMap, linkTo, err := scanEntries(pathes)
+	if err != nil {
+		return nil, err
+	}
+	if err := formatPathes(fileMap); err != nil {
+		return nil, &ErrCompression{err}
+	}
+	for i, target := range linkTo {
+		fileMap[i].LinkTarget = fileMap[target].Path
+	}
+	return fileMap, nil
+}
+
+// compressEntries сжимает обычные файлы fileMap, записывая данные в dst
+// с позиции offset. Кодеки нумеруются с firstCodec. end — конец данных.
+func compressEntries(
+	comps []CompressionBase, fallback FastCompressor, pathes []string, fileMap []File,
+	dst *os.File, offset int64, firstCodec int, prog *utiles.Progress[int64],
+) (codecs []Codec, bodies []Body, end int64, err error) {
+	var regular []int
+	for i := range fileMap {
+		if fileMap[i].Kind == KindFile {
+			regular = append(regular, i)
+		}
+	}
+	regularPathes := make([]string, len(regular))
+	for j, i := range regular {
+		regularPathes[j] = pathes[i]
+	}
+	opened, err := utiles.OpenFiles(regularPathes...)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer utiles.CloseFiles(opened)
+	srcs := make([]*os.File, len(pathes))
+	for j, i := range regular {
+		srcs[i] = opened[j]
+	}
+
+	type group struct {
+		c     CompressionBase
+		files []int
+	}
+	// группы файлов по компрессору в порядке первого появления,
+	// группа fallback сжимается последней, чтобы принять отвергнутые файлы
+	var (
+		groups        []*group
+		index         = make(map[CompressionBase]*group)
+		fallbackGroup = &group{c: fallback}
+	)
+	for _, i := range regular {
+		c := comps[i]
+		if fallback != nil && c == fallback {
+			fallbackGroup.files = append(fallbackGroup.files, i)
+			continue
+		}
+		g, ok := index[c]
+		if !ok {
+			g = &group{c: c}
+			index[c] = g
+			groups = append(groups, g)
+		}
+		g.files = append(g.files, i)
+	}
+	if fallback != nil {
+		groups = append(groups, fallbackGroup)
+	}
+
+	end = offset
+	for _, g := range groups {
+		if len(g.files) == 0 {
+			continue
+		}
+		groupSrcs := make([]*os.File, len(g.files))
+		for j, i := range g.files {
+			groupSrcs[j] = srcs[i]
+		}
+
+		var (
+			groupMap []File
+			rejected = make([]bool, len(g.files))
+		)
+		switch comp := g.c.(type) {
+		case FastCompressor:
+			canReject := fallback != nil && g != fallbackGroup
+			if groupMap, rejected, err = fastCompress(comp, groupSrcs, dst, end, canReject, prog); err != nil {
+				return nil, nil, 0, &ErrCompression{err}
+			}
+		case SimpleCompressor:
+			if groupMap, err = simpleCompress(comp, groupSrcs, dst, end, prog); err != nil {
+				return nil, nil, 0, &ErrCompression{err}
+			}
+		default:
+			return nil, nil, 0, fmt.Errorf("unsupported compressor type")
+		}
+
+		codec := firstCodec + len(codecs)
+		accepted := 0
+		for j, i := range g.files {
+			if rejected[j] {
+				fallbackGroup.files = append(fallbackGroup.files, i)
+				continue
+			}
+			entry := &fileMap[i]
+			entry.Checksum, entry.Offset = groupMap[j].Checksum, groupMap[j].Offset
+			entry.Size, entry.TrailingBits = groupMap[j].Size, groupMap[j].TrailingBits
+			entry.Codec = codec
+			end += groupMap[j].Size
+			accepted++
+		}
+		if accepted == 0 {
+			continue
+		}
+
+		name, body := g.c.CompressorData()
+		version := 0
+		if v, ok := g.c.(Versioned); ok {
+			version = v.FormatVersion()
+		}
+		codecs = append(codecs, Codec{name, version})
+		bodies = append(bodies, body)
+	}
+	return codecs, bodies, end, nil
+}
+
+// finishArchive записывает футер с позиции start и его размер в конце файла
+func finishArchive(footer *Footer, dst io.Writer, start int64) (footerSize int64, err error) {
+	footerSize, err = writeFooter(footer, dst, start)
+	if err != nil {
+		return 0, &ErrCompression{err}
+	}
+
+	// Write footer size at the end of the file
+	if err = binary.Write(dst, binary.LittleEndian, footerSize); err != nil {
+		return 0, &ErrCompression{fmt.Errorf("error while writing footer size: %v", err)}
+	}
+	return footerSize, nil
+}
+
+// compress handles compression for SimpleCompressor implementations.
+// Payloads are written to dst starting at offset.
+func simpleCompress(
+	c SimpleCompressor, srcs []*os.File, dst *os.File, offset int64, prog *utiles.Progress[int64],
+) ([]File, error) {
+	bufReaders := make([]*bufio.Reader, len(srcs))
+	readers := make([]io.Reader, len(srcs))
+
+	for i, f := range srcs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		bufReaders[i] = bufio.NewReader(f)
+		readers[i] = bufReaders[i]
+	}
+
+	if err := c.Preprocessing(readers); err != nil {
+		return nil, &ErrCompression{err}
+	}
+
+	fileMap := make([]File, len(srcs))
+
+	for i, f := range srcs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		bufReaders[i].Reset(f)
+
+		size, trailingBits, err := c.CompressFile(bufReaders[i], dst, prog)
+		if err != nil {
+			return nil, &ErrCompression{err}
+		}
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, &ErrCompression{err}
+		}
+		checksum, _ := checksum(f)
+
+		fileMap[i] = File{
+			Path:         f.Name(),
+			Checksum:     checksum,
+			Offset:       offset,
+			Size:         size,
+			TrailingBits: trailingBits,
+		}
+		offset += size
+	}
+	return fileMap, nil
+}
+
+// fastCompress handles compression for FastCompressor implementations
+// with concurrent writes starting at offset. If canReject is set, files
+// whose estimated size is not less than their own size are skipped
+// and marked in rejected.
+func fastCompress(
+	c FastCompressor, srcs []*os.File, dst *os.File, offset int64, canReject bool, prog *utiles.Progress[int64],
+) (fileMap []File, rejected []bool, err error) {
+	bufReaders := make([]*bufio.Reader, len(srcs))
+	readers := make([]io.Reader, len(srcs))
+
+	for i, f := range srcs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+		bufReaders[i] = bufio.NewReader(f)
+		readers[i] = bufReaders[i]
+	}
+
+	sizes, err := c.Preprocessing(readers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileMap = make([]File, len(srcs))
+	rejected = make([]bool, len(srcs))
+	if canReject {
+		if rejected, sizes, err = rejectFiles(c, srcs, bufReaders, sizes); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for i, f := range srcs {
+		if rejected[i] {
+			continue
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+		checksum, _ := checksum(f)
+
+		fileMap[i] = File{
+			Path:     f.Name(),
+			Checksum: checksum,
+			Offset:   offset,
+			Size:     sizes[i],
+		}
+		offset += sizes[i]
+	}
+
+	if err := dst.Truncate(offset); err != nil {
+		return nil, nil, err
+	}
+
+	var eg errgroup.Group
+	for i, f := range srcs {
+		if rejected[i] {
+			continue
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+		bufReaders[i].Reset(f)
+		writer := io.NewOffsetWriter(dst, fileMap[i].Offset)
+		eg.Go(func() (err error) {
+			_, fileMap[i].TrailingBits, err = c.CompressFile(bufReaders[i], writer, prog)
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := dst.Seek(0, io.SeekEnd); err != nil {
+		return nil, nil, err
+	}
+	return fileMap, rejected, nil
+}
+
+// rejectFiles отмечает файлы, которые не уменьшаются при сжатии. Пока
+// данные футера съедают весь выигрыш принятых файлов, отвергается файл
+// с худшим сжатием, и модель строится заново по остальным файлам.
+func rejectFiles(
+	c FastCompressor, srcs []*os.File, bufReaders []*bufio.Reader, sizes []int64,
+) ([]bool, []int64, error) {
+	rejected := make([]bool, len(srcs))
+	fileSizes := make([]int64, len(srcs))
+	for i, f := range srcs {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, nil, err
+		}
+		fileSizes[i] = info.Size()
+		rejected[i] = info.Size() > 0 && sizes[i] >= info.Size()
+	}
+
+	rebuild := slices.Contains(rejected, true)
+	for {
+		var accepted []int
+		for i := range srcs {
+			if !rejected[i] {
+				accepted = append(accepted, i)
+			}
+		}
+		if len(accepted) == 0 {
+			return rejected, sizes, nil
+		}
+
+		if rebuild {
+			readers := make([]io.Reader, len(accepted))
+			for k, i := range accepted {
+				if _, err := srcs[i].Seek(0, io.SeekStart); err != nil {
+					return nil, nil, err
+				}
+				bufReaders[i].Reset(srcs[i])
+				readers[k] = bufReaders[i]
+			}
+			acceptedSizes, err := c.Preprocessing(readers)
+			if err != nil {
+				return nil, nil, err
+			}
+			for k, i := range accepted {
+				sizes[i] = acceptedSizes[k]
+			}
+		}
+
+		var total, compressed int64
+		for _, i := range accepted {
+			total += fileSizes[i]
+			compressed += sizes[i]
+		}
+		_, body := c.CompressorData()
+		bodySize, err := BodySize(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		if total == 0 || compressed+bodySize < total {
+			return rejected, sizes, nil
+		}
+
+		// пустые файлы ничего не стоят и не отвергаются
+		worst, worstRatio := -1, 0.0
+		for _, i := range accepted {
+			if fileSizes[i] == 0 {
+				continue
+			}
+			if ratio := float64(sizes[i]) / float64(fileSizes[i]); worst < 0 || ratio > worstRatio {
+				worst, worstRatio = i, ratio
+			}
+		}
+		rejected[worst] = true
+		rebuild = true
+	}
+}
+
+// TrialSize compresses the sample with a fresh compressor and returns
+// the payload size together with the footer body size.
+func TrialSize(c CompressionBase, sample []byte) (int64, error) {
+	readers := []io.Reader{bytes.NewReader(sample)}
+	switch comp := c.(type) {
+	case FastCompressor:
+		if _, err := comp.Preprocessing(readers); err != nil {
+			return 0, err
+		}
+	case SimpleCompressor:
+		if err := comp.Preprocessing(readers); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("unsupported compressor type")
+	}
+
+	prog := utiles.NewProgress[int64](0)
+	prog.Close()
+	size, _, err := c.CompressFile(bytes.NewReader(sample), io.Discard, prog)
+	if err != nil {
+		return 0, err
+	}
+	_, body := c.CompressorData()
+	bodySize, err := BodySize(body)
+	if err != nil {
+		return 0, err
+	}
+	return size + bodySize, nil
+}
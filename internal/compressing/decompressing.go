@@ -1,121 +1,437 @@
-package compressing
-
-import (
-	"compressor/internal/utiles"
-	"encoding/binary"
-	"fmt"
-	"io"
-	"os"
-	"path/filepath"
-)
-
-type ErrDecompression struct{ Cause error }
-
-func (e *ErrDecompression) Error() string { return fmt.Sprintf("Decompression failed: %v", e.Cause) }
-func (e *ErrDecompression) Unwrap() error { return e.Cause }
-
-type Decompressor interface {
-	FooterBodyType() Body
-	Preprocessing(data Body, src io.ReadSeeker) error
-	DecompressFile(dd *DecompressionInput, prog *utiles.Progress[int64]) error
-}
-
-type DecompressionInput struct {
-	Body       Body
-	SourceFile io.Reader
-	DestFile   io.Writer
-}
-
-type DecompressedFile struct {
-	Path        string
-	OldChecksum string
-	NewChecksum string
-}
-
-type DecompressorFactory func(compType string) (d Decompressor)
-
-func Decompress(
-	factory DecompressorFactory, src *os.File, dstpath string, prog *utiles.Progress[int64],
-) ([]*DecompressedFile, error) {
-	md, mdSize, err := ReadFooterMetadata(src)
-	if err != nil {
-		return nil, &ErrDecompression{err}
-	}
-	prog.Write(mdSize)
-
-	decomp := factory(md.Type)
-
-	body := decomp.FooterBodyType()
-	bodySize, err := readFooterBody(src, body)
-	if err != nil {
-		return nil, &ErrDecompression{err}
-	}
-	prog.Write(bodySize)
-
-	if err := decomp.Preprocessing(body, src); err != nil {
-		return nil, err
-	}
-
-	if _, err := src.Seek(0, io.SeekStart); err != nil {
-		return nil, err
-	}
-	files := make([]*os.File, len(md.FileMap))
-	for i, file := range md.FileMap {
-		dir := filepath.Join(dstpath, filepath.Dir(file.Path))
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			removeFiles(files[:i])
-			return nil, &ErrDecompression{err}
-		}
-		path := filepath.Join(dstpath, file.Path)
-		files[i], err = os.Create(path)
-	}
-	defer utiles.CloseFiles(files)
-
-	if _, err := src.Seek(bodySize+mdSize, io.SeekStart); err != nil {
-		return nil, err
-	}
-	for i, f := range md.FileMap {
-		reader := io.NewSectionReader(src, f.Offset, f.Size)
-		input := &DecompressionInput{body, reader, files[i]}
-		if err = decomp.DecompressFile(input, prog); err != nil {
-			removeFiles(files)
-			return nil, &ErrDecompression{err}
-		}
-	}
-
-	output := make([]*DecompressedFile, len(files))
-	for i, f := range files {
-		if _, err := f.Seek(0, io.SeekStart); err != nil {
-			removeFiles(files)
-			return nil, err
-		}
-
-		oldChecksum := md.FileMap[i].Checksum
-		newChecksum, err := checksum(f)
-		if err != nil {
-			removeFiles(files)
-			return nil, &ErrDecompression{err}
-		}
-		output[i] = &DecompressedFile{f.Name(), oldChecksum, newChecksum}
-	}
-	return output, nil
-}
-
-func ReadFooterMetadata(file io.ReadSeeker) (md *Metadata, size int64, err error) {
-	if _, err := file.Seek(-8, io.SeekEnd); err != nil {
-		return nil, 0, &ErrDecompression{fmt.Errorf("error while reading footer size: %v", err)}
-	}
-	footerSize := int64(0)
-	if err = binary.Read(file, binary.LittleEndian, &footerSize); err != nil {
-		return nil, 0, &ErrFooterRead{err}
-	}
-
-	if _, err = file.Seek(-footerSize-8, io.SeekEnd); err != nil {
-		return nil, 0, &ErrFooterRead{err}
-	}
-	md, size, err = readFooterMetadata(file)
-	if err != nil {
-		return nil, 0, &ErrFooterRead{err}
-	}
-	return md, size, nil
-}
+package compressing
+
+import (
+	"compressor/internal/utiles"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type ErrDecompression struct{ Cause error }
+
+func (e *ErrDecompression) Error() string { return fmt.Sprintf("Decompression failed: %v", e.Cause) }
+func (e *ErrDecompression) Unwrap() error { return e.Cause }
+
+// ErrChecksumMismatch lists the entries whose decoded content differs
+// from the checksum stored in the archive.
+type ErrChecksumMismatch struct{ Paths []string }
+
+func (e *ErrChecksumMismatch) Error() string {
+	return fmt.Sprintf("checksum mismatch: %s", strings.Join(e.Paths, ", "))
+}
+
+type Decompressor interface {
+	FooterBodyType() Body
+	Preprocessing(data Body, src io.ReadSeeker) error
+	DecompressFile(dd *DecompressionInput, prog *utiles.Progress[int64]) error
+}
+
+type DecompressionInput struct {
+	Body       Body
+	SourceFile io.Reader
+	DestFile   io.Writer
+	Entry      File
+}
+
+type DecompressedFile struct {
+	Path        string
+	OldChecksum string
+	NewChecksum string
+	Err         error // decoding error, set by Verify
+}
+
+// DecompressorFactory returns the decompressor for the compression type
+// and its format version, or nil if the type is unknown.
+type DecompressorFactory func(compType string, version int) (d Decompressor)
+
+// Decompress extracts the archive to dstpath and verifies the checksum of
+// every extracted file. If some don't match, it returns *ErrChecksumMismatch
+// and handles the files according to opts.OnMismatch; with MismatchKeep and
+// MismatchDelete the output is returned along with the error.
+func Decompress(
+	factory DecompressorFactory, src *os.File, dstpath string, opts DecompressOptions,
+	prog *utiles.Progress[int64],
+) ([]*DecompressedFile, error) {
+	entries, codecs, format, err := readEntries(src, opts, prog)
+	if err != nil {
+		return nil, &ErrDecompression{err}
+	}
+
+	decomps, bodies, err := readCodecs(factory, src, codecs, format, usedCodecs(entries, len(codecs)), prog)
+	if err != nil {
+		return nil, err
+	}
+	regular, err := regularFiles(entries, len(decomps))
+	if err != nil {
+		return nil, &ErrDecompression{err}
+	}
+
+	if !opts.AllowUnsafePaths {
+		if err := checkPaths(entries); err != nil {
+			return nil, &ErrDecompression{err}
+		}
+	}
+
+	var dirs createdDirs
+	files := make([]*os.File, 0, len(regular))
+	defer func() { utiles.CloseFiles(files) }()
+	fail := func(err error) ([]*DecompressedFile, error) {
+		removeFiles(files)
+		dirs.remove()
+		return nil, &ErrDecompression{err}
+	}
+
+	if err := createDirs(entries, dstpath, opts, &dirs); err != nil {
+		return fail(err)
+	}
+	for _, i := range regular {
+		path, err := entryPath(dstpath, entries[i].Path, opts)
+		if err != nil {
+			return fail(err)
+		}
+		if err := dirs.mkdirAll(filepath.Dir(path)); err != nil {
+			return fail(err)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return fail(err)
+		}
+		files = append(files, f)
+	}
+
+	output := make([]*DecompressedFile, len(files))
+	var mismatch []string
+	for j, i := range regular {
+		f := entries[i]
+		hasher := sha256.New()
+		reader := io.NewSectionReader(src, f.Offset, f.Size)
+		input := &DecompressionInput{bodies[f.Codec], reader, io.MultiWriter(files[j], hasher), f}
+		if err = decomps[f.Codec].DecompressFile(input, prog); err != nil {
+			return fail(err)
+		}
+
+		newChecksum := hex.EncodeToString(hasher.Sum(nil))
+		output[j] = &DecompressedFile{Path: files[j].Name(), OldChecksum: f.Checksum, NewChecksum: newChecksum}
+		if newChecksum != f.Checksum {
+			mismatch = append(mismatch, f.Path)
+		}
+	}
+
+	return finishExtract(entries, regular, files, dirs, output, mismatch, dstpath, opts)
+}
+
+// finishExtract применяет opts.OnMismatch к распакованным файлам, создает
+// ссылки и восстанавливает атрибуты. files и output соответствуют
+// записям entries с номерами из regular, dirs — созданные каталоги.
+func finishExtract(
+	entries []File, regular []int, files []*os.File, dirs createdDirs, output []*DecompressedFile,
+	mismatch []string, dstpath string, opts DecompressOptions,
+) ([]*DecompressedFile, error) {
+	if len(mismatch) > 0 {
+		switch opts.OnMismatch {
+		case MismatchKeep:
+		case MismatchDelete:
+			removed := make(map[string]bool)
+			for j, i := range regular {
+				if output[j].NewChecksum != output[j].OldChecksum {
+					removeFiles(files[j : j+1])
+					removed[filepath.Clean(entries[i].Path)] = true
+				}
+			}
+			entries = withoutFiles(entries, removed)
+		default:
+			removeFiles(files)
+			dirs.remove()
+			return nil, &ErrChecksumMismatch{mismatch}
+		}
+	}
+
+	// ссылки создаются после файлов, чтобы запись файлов не шла через них
+	if err := createLinks(entries, dstpath, opts); err != nil {
+		removeFiles(files)
+		dirs.remove()
+		return nil, &ErrDecompression{err}
+	}
+
+	// атрибуты восстанавливаются последними: чтение меняет время доступа,
+	// а создание файлов — время изменения каталогов
+	if err := restoreAllAttributes(entries, dstpath, opts); err != nil {
+		return nil, &ErrDecompression{err}
+	}
+	if len(mismatch) > 0 {
+		return output, &ErrChecksumMismatch{mismatch}
+	}
+	return output, nil
+}
+
+// Verify decodes every regular file of the archive without writing it
+// and returns the stored and computed checksums. A file that can't be
+// decoded gets an empty NewChecksum and the error in Err.
+func Verify(factory DecompressorFactory, src *os.File, prog *utiles.Progress[int64]) ([]*DecompressedFile, error) {
+	md, mdSize, err := ReadFooterMetadata(src)
+	if err != nil {
+		return nil, &ErrDecompression{err}
+	}
+	prog.Write(mdSize)
+
+	decomps, bodies, err := readCodecs(factory, src, md.EntryCodecs(), md.format, nil, prog)
+	if err != nil {
+		return nil, err
+	}
+	regular, err := regularFiles(md.FileMap, len(decomps))
+	if err != nil {
+		return nil, &ErrDecompression{err}
+	}
+
+	output := make([]*DecompressedFile, len(regular))
+	for j, i := range regular {
+		f := md.FileMap[i]
+		output[j] = &DecompressedFile{Path: f.Path, OldChecksum: f.Checksum}
+		hasher := sha256.New()
+		reader := io.NewSectionReader(src, f.Offset, f.Size)
+		input := &DecompressionInput{bodies[f.Codec], reader, hasher, f}
+		if err := decomps[f.Codec].DecompressFile(input, prog); err != nil {
+			output[j].Err = err
+			continue
+		}
+		output[j].NewChecksum = hex.EncodeToString(hasher.Sum(nil))
+	}
+	return output, nil
+}
+
+// readEntries читает записи, выбранные opts.Include и opts.Exclude, и
+// оставляет src в начале секций тел кодеков. Если все шаблоны include —
+// пути без символов шаблона, записи ищутся по индексу, а остальные
+// метаданные не читаются.
+func readEntries(
+	src *os.File, opts DecompressOptions, prog *utiles.Progress[int64],
+) (entries []File, codecs []Codec, format uint16, err error) {
+	if literalPathes(opts.Include) {
+		ix, err := OpenIndex(src)
+		if err == nil {
+			if entries, err = indexEntries(ix, opts.Include, opts.Exclude); err != nil {
+				return nil, nil, 0, err
+			}
+			if _, err := src.Seek(ix.end, io.SeekStart); err != nil {
+				return nil, nil, 0, err
+			}
+			if _, err := skipSection(src); err != nil {
+				return nil, nil, 0, &ErrFooterRead{err}
+			}
+			return entries, ix.Codecs, ix.format, nil
+		} else if !errors.Is(err, ErrNoIndex) {
+			return nil, nil, 0, err
+		}
+	}
+
+	md, mdSize, err := ReadFooterMetadata(src)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	prog.Write(mdSize)
+	entries, err = selectEntries(md.FileMap, opts.Include, opts.Exclude)
+	return entries, md.EntryCodecs(), md.format, err
+}
+
+// usedCodecs отмечает кодеки обычных файлов entries
+func usedCodecs(entries []File, codecs int) []bool {
+	used := make([]bool, codecs)
+	for _, e := range entries {
+		if e.Kind == KindFile && e.Codec >= 0 && e.Codec < codecs {
+			used[e.Codec] = true
+		}
+	}
+	return used
+}
+
+// readCodecs читает тела кодеков из футера и подготавливает декомпрессоры.
+// Тела кодеков, не отмеченных в used, пропускаются; при used == nil
+// читаются все.
+func readCodecs(
+	factory DecompressorFactory, src *os.File, codecs []Codec, format uint16, used []bool,
+	prog *utiles.Progress[int64],
+) ([]Decompressor, []Body, error) {
+	decomps := make([]Decompressor, len(codecs))
+	bodies := make([]Body, len(codecs))
+	for i, codec := range codecs {
+		if used != nil && !used[i] {
+			if _, err := skipSection(src); err != nil {
+				return nil, nil, &ErrDecompression{&ErrFooterRead{err}}
+			}
+			continue
+		}
+		decomps[i] = factory(codec.Type, codec.Version)
+		if decomps[i] == nil {
+			return nil, nil, &ErrDecompression{fmt.Errorf("unsupported compression type: %s", codec.Type)}
+		}
+
+		bodies[i] = decomps[i].FooterBodyType()
+		bodySize, err := readFooterBody(src, bodies[i], format)
+		if err != nil {
+			return nil, nil, &ErrDecompression{err}
+		}
+		prog.Write(bodySize)
+
+		if err := decomps[i].Preprocessing(bodies[i], src); err != nil {
+			return nil, nil, err
+		}
+	}
+	return decomps, bodies, nil
+}
+
+// regularFiles возвращает номера обычных файлов и проверяет виды записей
+func regularFiles(entries []File, codecs int) ([]int, error) {
+	var regular []int
+	for i, f := range entries {
+		switch f.Kind {
+		case KindFile:
+			if f.Codec < 0 || f.Codec >= codecs {
+				return nil, fmt.Errorf("unknown codec %d of %s", f.Codec, f.Path)
+			}
+			regular = append(regular, i)
+		case KindDir, KindSymlink, KindHardlink:
+		default:
+			return nil, fmt.Errorf("unknown entry kind %v of %s", f.Kind, f.Path)
+		}
+	}
+	return regular, nil
+}
+
+// DecompressEntry decodes the regular file at path, or the file of the
+// hard link at path, to dst and verifies its checksum. Only the footer
+// sections up to the codec of the entry and its payload are read.
+func DecompressEntry(factory DecompressorFactory, src *os.File, path string, dst io.Writer) error {
+	entry, codecs, format, err := findEntry(src, path)
+	if err != nil {
+		return &ErrDecompression{err}
+	}
+	if entry.Codec < 0 || entry.Codec >= len(codecs) {
+		return &ErrDecompression{fmt.Errorf("unknown codec %d of %s", entry.Codec, entry.Path)}
+	}
+
+	// секции тел кодеков идут подряд, тела предыдущих кодеков не нужны
+	for i := 0; i < entry.Codec; i++ {
+		if _, err := skipSection(src); err != nil {
+			return &ErrDecompression{&ErrFooterRead{err}}
+		}
+	}
+	codec := codecs[entry.Codec]
+	decomp := factory(codec.Type, codec.Version)
+	if decomp == nil {
+		return &ErrDecompression{fmt.Errorf("unsupported compression type: %s", codec.Type)}
+	}
+	body := decomp.FooterBodyType()
+	if _, err := readFooterBody(src, body, format); err != nil {
+		return &ErrDecompression{err}
+	}
+	if err := decomp.Preprocessing(body, src); err != nil {
+		return err
+	}
+
+	prog := utiles.NewProgress[int64](0)
+	prog.Close()
+	hasher := sha256.New()
+	reader := io.NewSectionReader(src, entry.Offset, entry.Size)
+	input := &DecompressionInput{body, reader, io.MultiWriter(dst, hasher), *entry}
+	if err := decomp.DecompressFile(input, prog); err != nil {
+		return &ErrDecompression{err}
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != entry.Checksum {
+		return &ErrChecksumMismatch{[]string{entry.Path}}
+	}
+	return nil
+}
+
+// findEntry ищет обычный файл по индексу, а в архивах без индекса — по
+// метаданным, и оставляет src в начале секций тел кодеков
+func findEntry(src *os.File, path string) (entry *File, codecs []Codec, format uint16, err error) {
+	ix, err := OpenIndex(src)
+	if errors.Is(err, ErrNoIndex) {
+		md, _, err := ReadFooterMetadata(src)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		entry, err = findFile(md.FileMap, path)
+		return entry, md.EntryCodecs(), md.format, err
+	} else if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if entry, err = ix.Lookup(path); err != nil {
+		return nil, nil, 0, err
+	}
+	switch entry.Kind {
+	case KindFile:
+	case KindHardlink:
+		link := entry
+		if entry, err = ix.Lookup(link.LinkTarget); err != nil || entry.Kind != KindFile {
+			return nil, nil, 0, fmt.Errorf("hard link %s points to %s, which is not a file of the archive", link.Path, link.LinkTarget)
+		}
+	default:
+		return nil, nil, 0, fmt.Errorf("%s is a %v, not a regular file", entry.Path, entry.Kind)
+	}
+	if _, err := src.Seek(ix.end, io.SeekStart); err != nil {
+		return nil, nil, 0, err
+	}
+	if _, err := skipSection(src); err != nil {
+		return nil, nil, 0, &ErrFooterRead{err}
+	}
+	return entry, ix.Codecs, ix.format, nil
+}
+
+// ReadFooterMetadata validates the archive header and reads the footer
+// metadata. Archives without a header are read as written before it.
+func ReadFooterMetadata(file io.ReadSeeker) (md *Metadata, size int64, err error) {
+	dataStart, format := HeaderSize, uint16(0)
+	header, err := ReadHeader(file)
+	var notArchive *ErrNotArchive
+	legacy := errors.As(err, &notArchive)
+	if legacy {
+		dataStart = 0
+	} else if err != nil {
+		return nil, 0, err
+	} else {
+		format = header.Version
+	}
+
+	md, size, err = readFooter(file, dataStart, format)
+	if err != nil {
+		if legacy {
+			return nil, 0, &ErrNotArchive{}
+		}
+		return nil, 0, &ErrFooterRead{err}
+	}
+	return md, size, nil
+}
+
+func readFooter(file io.ReadSeeker, dataStart int64, format uint16) (md *Metadata, size int64, err error) {
+	end, err := file.Seek(-8, io.SeekEnd)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error while reading footer size: %v", err)
+	}
+	footerSize := int64(0)
+	if err = binary.Read(file, binary.LittleEndian, &footerSize); err != nil {
+		return nil, 0, err
+	}
+	if footerSize <= 0 || footerSize > end-dataStart {
+		return nil, 0, fmt.Errorf("invalid footer size: %d", footerSize)
+	}
+
+	if _, err = file.Seek(-footerSize-8, io.SeekEnd); err != nil {
+		return nil, 0, err
+	}
+	indexSize := int64(0)
+	if format >= indexFooterVersion {
+		if indexSize, err = skipSection(file); err != nil {
+			return nil, 0, err
+		}
+	}
+	md, size, err = readFooterMetadata(file, format)
+	return md, size + indexSize, err
+}
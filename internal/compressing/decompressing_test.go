@@ -0,0 +1,143 @@
+package compressing
+
+import (
+	"compressor/internal/utiles"
+	"errors"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// copyDecompressor копирует данные записи без декодирования
+type copyDecompressor struct{}
+
+func (copyDecompressor) FooterBodyType() Body                        { return &testBody{} }
+func (copyDecompressor) Preprocessing(_ Body, _ io.ReadSeeker) error { return nil }
+func (copyDecompressor) DecompressFile(dd *DecompressionInput, _ *utiles.Progress[int64]) error {
+	_, err := io.Copy(dd.DestFile, dd.SourceFile)
+	return err
+}
+
+// Кодек BAD неизвестен, а запись d/w.txt в метаданных испорчена: архив
+// распаковывается, только если ни то, ни другое не читается.
+func writeSelectiveArchive(t *testing.T) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "select.dedal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	if err := writeHeader(newHeader(0), f); err != nil {
+		t.Fatal(err)
+	}
+
+	offset := HeaderSize
+	file := func(path, data string, codec int) File {
+		sum, _ := checksum(strings.NewReader(data))
+		if _, err := f.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+		e := File{Path: filepath.FromSlash(path), Checksum: sum, Offset: offset, Size: int64(len(data)), Codec: codec, Mode: 0o644}
+		offset += int64(len(data))
+		return e
+	}
+	files := []File{
+		{Path: "a", Kind: KindDir, Mode: 0o755},
+		file("a/x.txt", "x", 1),
+		file("a/b/y.txt", "yy", 1),
+		{Path: filepath.FromSlash("a/h"), Kind: KindHardlink, LinkTarget: filepath.FromSlash("c/z.txt")},
+		file("c/z.txt", "zzz", 1),
+		file("d/w.txt", "w", 0),
+	}
+	codecs := []Codec{{"BAD", 0}, {"COPY", 0}}
+	if _, err := finishArchive(newFooter(codecs, files, []Body{&testBody{}, &testBody{}}), f, offset); err != nil {
+		t.Fatal(err)
+	}
+
+	ix, err := OpenIndex(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := 0; k < ix.count; k++ {
+		rec, err := ix.record(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e, err := ix.readFile(rec); err != nil || e.Path != filepath.FromSlash("d/w.txt") {
+			continue
+		}
+		if _, err := f.WriteAt([]byte(strings.Repeat("\xff", int(rec.size))), rec.offset); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+// extractedFiles возвращает содержимое распакованных файлов по путям с '/'
+func extractedFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestDecompressIndexed(t *testing.T) {
+	factory := func(compType string, _ int) Decompressor {
+		if compType == "COPY" {
+			return copyDecompressor{}
+		}
+		return nil
+	}
+	cases := []struct {
+		name             string
+		include, exclude []string
+		want             map[string]string
+	}{
+		{"file", []string{"a/b/y.txt"}, nil, map[string]string{"a/b/y.txt": "yy"}},
+		{"unclean", []string{"./a/b/"}, nil, map[string]string{"a/b/y.txt": "yy"}},
+		{"dir with link copy", []string{"a/b", "a/h"}, nil, map[string]string{"a/b/y.txt": "yy", "a/h": "zzz"}},
+		{"excluded", []string{"a/b/", "a/x.txt"}, []string{"x.txt"}, map[string]string{"a/b/y.txt": "yy"}},
+		{"link with file", []string{"a/h", "c/z.txt"}, nil, map[string]string{"a/h": "zzz", "c/z.txt": "zzz"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			prog := utiles.NewProgress[int64](0)
+			prog.Close()
+			dst := t.TempDir()
+			opts := DecompressOptions{NoPerms: true, NoOwner: true, Include: c.include, Exclude: c.exclude}
+			if _, err := Decompress(factory, writeSelectiveArchive(t), dst, opts, prog); err != nil {
+				t.Fatal(err)
+			}
+			if got := extractedFiles(t, dst); !maps.Equal(got, c.want) {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	// имя без '/' и пути, которых нет, не находятся по индексу
+	for _, include := range [][]string{{"y.txt"}, {"a/b/y.txt", "a/missing"}} {
+		prog := utiles.NewProgress[int64](0)
+		prog.Close()
+		opts := DecompressOptions{Include: include}
+		if _, err := Decompress(factory, writeSelectiveArchive(t), t.TempDir(), opts, prog); err == nil {
+			t.Fatalf("%v: archive with a broken record was extracted", include)
+		} else if strings.Contains(include[len(include)-1], "missing") && !errors.As(err, new(*ErrNoMatch)) {
+			t.Fatalf("%v: got %v, want ErrNoMatch", include, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package compressing
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Encoder builds the binary footer sections described in Format.md:
+// unsigned integers are varints (LEB128), strings and byte slices
+// are prefixed with their length.
+type Encoder struct {
+	buf []byte
+}
+
+func NewEncoder() *Encoder { return &Encoder{} }
+
+func (e *Encoder) Bytes() []byte { return e.buf }
+
+func (e *Encoder) Uvarint(v uint64) { e.buf = binary.AppendUvarint(e.buf, v) }
+
+// Varint записывает знаковое число в zig-zag кодировке
+func (e *Encoder) Varint(v int64) { e.buf = binary.AppendVarint(e.buf, v) }
+
+func (e *Encoder) Byte(b byte) { e.buf = append(e.buf, b) }
+
+func (e *Encoder) Blob(b []byte) {
+	e.Uvarint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *Encoder) String(s string) {
+	e.Uvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+var errShortSection = errors.New("unexpected end of footer section")
+
+// Decoder reads values written by Encoder. The first error is kept
+// and returned by Err, later reads return zero values.
+type Decoder struct {
+	buf []byte
+	err error
+}
+
+func NewDecoder(data []byte) *Decoder { return &Decoder{buf: data} }
+
+func (d *Decoder) Err() error { return d.err }
+
+// Len returns the number of unread bytes.
+func (d *Decoder) Len() int { return len(d.buf) }
+
+func (d *Decoder) Uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errShortSection
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *Decoder) Varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errShortSection
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// Int читает неотрицательное число, которое должно поместиться в int
+func (d *Decoder) Int() int {
+	v := d.Uvarint()
+	if v > uint64(maxInt) {
+		d.fail(fmt.Errorf("value out of range: %d", v))
+		return 0
+	}
+	return int(v)
+}
+
+func (d *Decoder) Byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) == 0 {
+		d.err = errShortSection
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *Decoder) Blob() []byte {
+	n := d.Uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.buf)) {
+		d.err = errShortSection
+		return nil
+	}
+	b := d.buf[:n:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *Decoder) String() string { return string(d.Blob()) }
+
+// Count читает число элементов, каждый из которых занимает не меньше
+// minSize байт, чтобы испорченный размер не приводил к большим выделениям памяти
+func (d *Decoder) Count(minSize int) int {
+	n := d.Int()
+	if d.err == nil && n > len(d.buf)/max(minSize, 1) {
+		d.err = errShortSection
+		return 0
+	}
+	return n
+}
+
+func (d *Decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+const maxInt = int(^uint(0) >> 1)
@@ -0,0 +1,374 @@
+package compressing
+
+import (
+	"compressor/internal/utiles"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type ErrUnsafeLink struct{ Path, Target string }
+
+func (e *ErrUnsafeLink) Error() string {
+	return fmt.Sprintf("symlink %s -> %s points outside the destination", e.Path, e.Target)
+}
+
+// ErrNoMatch is returned when an include pattern matches no entry of the archive.
+type ErrNoMatch struct{ Pattern string }
+
+func (e *ErrNoMatch) Error() string { return fmt.Sprintf("%s: not found in archive", e.Pattern) }
+
+// selectEntries оставляет записи, путь которых или один из родительских
+// каталогов подходит под какой-нибудь шаблон include (все записи, если
+// include пуст) и ни под один шаблон exclude.
+func selectEntries(entries []File, include, exclude []string) ([]File, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return entries, nil
+	}
+
+	matched := make([]bool, len(include))
+	selected := make([]bool, len(entries))
+	for i, e := range entries {
+		var err error
+		if selected[i], err = isSelected(e.Path, include, exclude, matched); err != nil {
+			return nil, err
+		}
+	}
+	for j, p := range include {
+		if !matched[j] {
+			return nil, &ErrNoMatch{p}
+		}
+	}
+
+	return keepEntries(entries, selected), nil
+}
+
+// literalPathes сообщает, что каждый шаблон — путь от корня архива без
+// символов шаблона. Шаблон без '/' подходит к именам на любом уровне,
+// поэтому по индексу его не найти.
+func literalPathes(patterns []string) bool {
+	for _, p := range patterns {
+		p = path.Clean(filepath.ToSlash(p))
+		if !strings.Contains(p, "/") || path.IsAbs(p) || strings.HasPrefix(p, "../") ||
+			strings.ContainsAny(p, `*?[\`) {
+			return false
+		}
+	}
+	return len(patterns) > 0
+}
+
+// indexEntries выбирает записи по индексу, как selectEntries: каждый путь
+// include вместе с содержимым каталога. Пути include должны быть
+// проверены literalPathes.
+func indexEntries(ix *Index, include, exclude []string) ([]File, error) {
+	var found []File
+	seen := make(map[string]bool)
+	add := func(f File) {
+		if p := filepath.Clean(f.Path); !seen[p] {
+			seen[p] = true
+			found = append(found, f)
+		}
+	}
+	for _, p := range include {
+		clean := filepath.FromSlash(path.Clean(filepath.ToSlash(p)))
+		f, err := ix.Lookup(clean)
+		if err != nil && !errors.As(err, new(*ErrNoMatch)) {
+			return nil, err
+		}
+		// у файлов и ссылок нет содержимого, искать его не нужно
+		var inside []File
+		if f == nil || f.Kind == KindDir {
+			if inside, err = ix.List(clean); err != nil {
+				return nil, err
+			}
+		}
+		if f == nil && len(inside) == 0 {
+			return nil, &ErrNoMatch{p}
+		}
+		if f != nil {
+			add(*f)
+		}
+		for _, e := range inside {
+			add(e)
+		}
+	}
+
+	selected := make([]bool, len(found))
+	for i, e := range found {
+		var err error
+		if selected[i], err = isSelected(e.Path, nil, exclude, nil); err != nil {
+			return nil, err
+		}
+	}
+	// keepEntries заменяет жесткую ссылку копией файла, если файл не выбран,
+	// поэтому файлы ссылок добавляются в список невыбранными
+	for _, e := range found {
+		if e.Kind != KindHardlink || seen[filepath.Clean(e.LinkTarget)] {
+			continue
+		}
+		target, err := ix.Lookup(e.LinkTarget)
+		if errors.As(err, new(*ErrNoMatch)) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		add(*target)
+		selected = append(selected, false)
+	}
+	return keepEntries(found, selected), nil
+}
+
+// isSelected проверяет одну запись по шаблонам include и exclude
+func isSelected(path string, include, exclude []string, matched []bool) (bool, error) {
+	inc, err := matchEntry(include, path, matched)
+	if err != nil {
+		return false, err
+	}
+	exc, err := matchEntry(exclude, path, nil)
+	if err != nil {
+		return false, err
+	}
+	return (inc || len(include) == 0) && !exc, nil
+}
+
+// keepEntries оставляет отмеченные записи. Жесткая ссылка на
+// неотмеченный файл заменяется копией этого файла.
+func keepEntries(entries []File, selected []bool) []File {
+	files := make(map[string]int)
+	for i, e := range entries {
+		if e.Kind == KindFile {
+			files[filepath.Clean(e.Path)] = i
+		}
+	}
+	var result []File
+	for i, e := range entries {
+		if !selected[i] {
+			continue
+		}
+		if t, ok := files[filepath.Clean(e.LinkTarget)]; e.Kind == KindHardlink && ok && !selected[t] {
+			file := entries[t]
+			file.Path = e.Path
+			e = file
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+// matchEntry проверяет, подходит ли путь или один из его родительских
+// каталогов под какой-нибудь из шаблонов, и отмечает подошедшие в matched.
+// Шаблон без '/' сравнивается с именем на любом уровне, как в .gitignore.
+func matchEntry(patterns []string, name string, matched []bool) (bool, error) {
+	name = filepath.ToSlash(filepath.Clean(name))
+	found := false
+	for j, p := range patterns {
+		p = path.Clean(filepath.ToSlash(p))
+		if !strings.Contains(p, "/") {
+			p = "**/" + p
+		}
+		for n := name; n != "." && n != "/"; n = path.Dir(n) {
+			ok, err := utiles.MatchGlob(p, n)
+			if err != nil {
+				return false, fmt.Errorf("bad pattern %q: %w", patterns[j], err)
+			}
+			if ok {
+				found = true
+				if matched != nil {
+					matched[j] = true
+				}
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+// withoutFiles убирает обычные файлы с путями из removed и жесткие ссылки на них
+func withoutFiles(entries []File, removed map[string]bool) []File {
+	return slices.DeleteFunc(slices.Clone(entries), func(e File) bool {
+		return e.Kind == KindFile && removed[filepath.Clean(e.Path)] ||
+			e.Kind == KindHardlink && removed[filepath.Clean(e.LinkTarget)]
+	})
+}
+
+// findFile ищет обычный файл по пути. Для жесткой ссылки возвращается
+// файл, на который она указывает.
+func findFile(entries []File, path string) (*File, error) {
+	path = filepath.Clean(path)
+	for i := range entries {
+		e := &entries[i]
+		if filepath.Clean(e.Path) != path {
+			continue
+		}
+		switch e.Kind {
+		case KindFile:
+			return e, nil
+		case KindHardlink:
+			target := filepath.Clean(e.LinkTarget)
+			for j := range entries {
+				if entries[j].Kind == KindFile && filepath.Clean(entries[j].Path) == target {
+					return &entries[j], nil
+				}
+			}
+			return nil, fmt.Errorf("hard link %s points to %s, which is not a file of the archive", e.Path, e.LinkTarget)
+		default:
+			return nil, fmt.Errorf("%s is a %v, not a regular file", e.Path, e.Kind)
+		}
+	}
+	return nil, &ErrNoMatch{path}
+}
+
+// createDirs создает каталоги архива и добавляет новые каталоги в dirs
+func createDirs(entries []File, dstpath string, opts DecompressOptions, dirs *createdDirs) error {
+	for _, e := range entries {
+		if e.Kind != KindDir {
+			continue
+		}
+		path, err := entryPath(dstpath, e.Path, opts)
+		if err != nil {
+			return err
+		}
+		if err := dirs.mkdirAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createLinks создает жесткие и символические ссылки. Жесткая ссылка должна
+// указывать на обычный файл архива, символическая — не выходить за dstpath.
+func createLinks(entries []File, dstpath string, opts DecompressOptions) error {
+	files := make(map[string]bool)
+	symlinks := make(map[string]string)
+	for _, e := range entries {
+		switch e.Kind {
+		case KindFile:
+			files[filepath.Clean(e.Path)] = true
+		case KindSymlink:
+			symlinks[filepath.Clean(e.Path)] = e.LinkTarget
+		}
+	}
+	// все цели проверяются до создания первой ссылки
+	for _, e := range entries {
+		if e.Kind == KindSymlink && !opts.AllowUnsafePaths && !symlinkInside(symlinks, e.Path, e.LinkTarget) {
+			return &ErrUnsafeLink{e.Path, e.LinkTarget}
+		}
+	}
+
+	for _, e := range entries {
+		if e.Kind != KindHardlink && e.Kind != KindSymlink {
+			continue
+		}
+		path, err := entryPath(dstpath, e.Path, opts)
+		if err != nil {
+			return err
+		}
+		switch e.Kind {
+		case KindHardlink:
+			if !files[filepath.Clean(e.LinkTarget)] {
+				return fmt.Errorf("hard link %s points to %s, which is not a file of the archive", e.Path, e.LinkTarget)
+			}
+			target, err := entryPath(dstpath, e.LinkTarget, opts)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := os.Link(target, path); err != nil {
+				return err
+			}
+		case KindSymlink:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(e.LinkTarget, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// maxLinkHops — предел переходов по ссылкам при проверке, как ELOOP у ОС
+const maxLinkHops = 40
+
+// symlinkInside проверяет, что ссылка с путем path внутри архива
+// указывает на путь внутри архива. Цель разбирается так же, как ее
+// разберет ОС: через ссылки symlinks, которые архив уже создал или
+// создаст, поэтому цепочка ссылок не выведет за пределы архива.
+func symlinkInside(symlinks map[string]string, path, target string) bool {
+	hops := 0
+	dir := strings.FieldsFunc(filepath.Dir(filepath.Clean(path)), isSeparator)
+	if len(dir) == 1 && dir[0] == "." {
+		dir = nil
+	}
+	_, ok := resolveLink(symlinks, dir, target, &hops)
+	return ok
+}
+
+// resolveLink разбирает target от каталога dir (части пути внутри
+// архива) и возвращает полученный путь. false означает, что путь выходит
+// за пределы архива или ссылки образуют цикл.
+func resolveLink(symlinks map[string]string, dir []string, target string, hops *int) ([]string, bool) {
+	if target == "" || filepath.IsAbs(target) || filepath.VolumeName(target) != "" || isSeparator(rune(target[0])) {
+		return nil, false
+	}
+	current := slices.Clone(dir)
+	for _, part := range strings.FieldsFunc(target, isSeparator) {
+		switch part {
+		case ".":
+			continue
+		case "..":
+			if len(current) == 0 {
+				return nil, false
+			}
+			current = current[:len(current)-1]
+			continue
+		}
+		current = append(current, part)
+		next, ok := symlinks[filepath.Join(current...)]
+		if !ok {
+			continue
+		}
+		if *hops++; *hops > maxLinkHops {
+			return nil, false
+		}
+		if current, ok = resolveLink(symlinks, current[:len(current)-1], next, hops); !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// restoreAllAttributes восстанавливает атрибуты всех записей. Каталоги
+// обрабатываются последними, вложенные раньше родительских.
+func restoreAllAttributes(entries []File, dstpath string, opts DecompressOptions) error {
+	var dirs []*File
+	for i := range entries {
+		e := &entries[i]
+		switch e.Kind {
+		case KindDir:
+			dirs = append(dirs, e)
+			continue
+		case KindHardlink:
+			// атрибуты общие с файлом, на который указывает ссылка
+			continue
+		}
+		if err := restoreAttributes(filepath.Join(dstpath, e.Path), e, opts); err != nil {
+			return err
+		}
+	}
+
+	slices.SortFunc(dirs, func(a, b *File) int { return strings.Compare(b.Path, a.Path) })
+	for _, e := range dirs {
+		if err := restoreAttributes(filepath.Join(dstpath, e.Path), e, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package compressing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSymlinkInside(t *testing.T) {
+	cases := []struct {
+		name     string
+		symlinks map[string]string // другие ссылки архива
+		path     string
+		target   string
+		inside   bool
+	}{
+		{"sibling", nil, "a/l", "f", true},
+		{"parent", nil, "a/l", "..", true},
+		{"root file", nil, "l", "a/../f", true},
+		{"escape", nil, "a/l", "../..", false},
+		{"absolute", nil, "a/l", "/etc", false},
+		{"empty", nil, "a/l", "", false},
+		{"through link", map[string]string{"a/q": ".."}, "a/p", "q/../..", false},
+		{"through link inside", map[string]string{"a/q": "../b"}, "a/p", "q/../f", true},
+		{"through nested links", map[string]string{"a/q": "b/r", "a/b/r": "../../.."}, "a/p", "q/f", false},
+		{"link to escaping link", map[string]string{"a/q": "../.."}, "a/p", "q", false},
+		{"loop", map[string]string{"a/x": "y", "a/y": "x"}, "a/p", "x", false},
+		{"loop to itself", nil, "a/p", "p/f", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			symlinks := map[string]string{filepath.FromSlash(c.path): c.target}
+			for p, target := range c.symlinks {
+				symlinks[filepath.FromSlash(p)] = target
+			}
+			got := symlinkInside(symlinks, filepath.FromSlash(c.path), c.target)
+			if got != c.inside {
+				t.Fatalf("symlinkInside(%s -> %s) = %v, want %v", c.path, c.target, got, c.inside)
+			}
+		})
+	}
+}
+
+// Ссылка a/p -> q/../.. проходит через ссылку a/q -> .. и указывает на
+// родителя каталога распаковки, хотя по тексту цели остается внутри.
+func TestCreateLinksChainedEscape(t *testing.T) {
+	q := File{Path: filepath.FromSlash("a/q"), Kind: KindSymlink, LinkTarget: ".."}
+	p := File{Path: filepath.FromSlash("a/p"), Kind: KindSymlink, LinkTarget: "q/../.."}
+	dir := File{Path: "a", Kind: KindDir}
+	for name, entries := range map[string][]File{
+		"q first": {dir, q, p},
+		"p first": {dir, p, q},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "ex", "one", "two", "d")
+			var dirs createdDirs
+			if err := createDirs(entries, dst, DecompressOptions{}, &dirs); err != nil {
+				t.Fatal(err)
+			}
+			err := createLinks(entries, dst, DecompressOptions{})
+			var unsafeLink *ErrUnsafeLink
+			if !errors.As(err, &unsafeLink) || unsafeLink.Path != p.Path {
+				t.Fatalf("got %v, want ErrUnsafeLink for %s", err, p.Path)
+			}
+			if _, err := os.Lstat(filepath.Join(dst, p.Path)); !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("%s was created: %v", p.Path, err)
+			}
+		})
+	}
+}
@@ -1,106 +1,405 @@
-package compressing
-
-import (
-	"bytes"
-	"encoding/binary"
-	"encoding/gob"
-	"fmt"
-	"io"
-)
-
-type ErrFooterRead struct{ Cause error }
-
-func (e *ErrFooterRead) Error() string { return fmt.Sprintf("footer can't be readed: %v", e.Cause) }
-func (e *ErrFooterRead) Unwrap() error { return e.Cause }
-
-type ErrFooterWrite struct{ Cause error }
-
-func (e *ErrFooterWrite) Error() string { return fmt.Sprintf("footer can't be written: %v", e.Cause) }
-func (e *ErrFooterWrite) Unwrap() error { return e.Cause }
-
-type File struct {
-	Path     string // relative path
-	Checksum string
-	Offset   int64
-	Size     int64
-}
-
-type Metadata struct {
-	Type    string
-	FileMap []File
-}
-
-type Body any
-
-type Footer struct {
-	Metadata
-	Body
-}
-
-func newFooter(compType string, fileMap []File, body Body) *Footer {
-	return &Footer{Metadata{compType, fileMap}, body}
-}
-
-func write(data any, file io.Writer) (size int64, err error) {
-	var buffer bytes.Buffer
-	encoder := gob.NewEncoder(&buffer)
-
-	gob.Register(&data)
-	if err := encoder.Encode(data); err != nil {
-		return 0, err
-	}
-
-	size = int64(buffer.Len())
-	if err := binary.Write(file, binary.LittleEndian, size); err != nil {
-		return 0, err
-	}
-
-	if err := binary.Write(file, binary.LittleEndian, buffer.Bytes()); err != nil {
-		return 0, err
-	}
-	return size + 8, nil
-}
-
-func writeFooter(footer *Footer, file io.Writer) (size int64, err error) {
-	mdSize, err := write(footer.Metadata, file)
-	if err != nil {
-		return 0, &ErrFooterWrite{err}
-	}
-	bodySize, err := write(footer.Body, file)
-	if err != nil {
-		return 0, &ErrFooterWrite{err}
-	}
-
-	return bodySize + mdSize, nil
-}
-
-func read(file io.ReadSeeker, dataType any) (size int64, err error) {
-	if err = binary.Read(file, binary.LittleEndian, &size); err != nil {
-		return 0, err
-	}
-
-	buf := make([]byte, size)
-	if err = binary.Read(file, binary.LittleEndian, buf); err != nil {
-		return 0, err
-	}
-
-	dec := gob.NewDecoder(bytes.NewBuffer(buf))
-	gob.Register(dataType)
-	if err = dec.Decode(dataType); err != nil {
-		return 0, err
-	}
-
-	return size + 8, nil
-}
-
-func readFooterBody(file io.ReadSeeker, footerDataType Body) (size int64, err error) {
-	return read(file, footerDataType)
-}
-
-func readFooterMetadata(file io.ReadSeeker) (md *Metadata, size int64, err error) {
-	size, err = read(file, &md)
-	if err != nil {
-		return nil, 0, err
-	}
-	return md, size, nil
-}
+package compressing
+
+import (
+	"bytes"
+	"compressor/internal/utiles"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+)
+
+type ErrFooterRead struct{ Cause error }
+
+func (e *ErrFooterRead) Error() string { return fmt.Sprintf("footer can't be readed: %v", e.Cause) }
+func (e *ErrFooterRead) Unwrap() error { return e.Cause }
+
+type ErrFooterWrite struct{ Cause error }
+
+func (e *ErrFooterWrite) Error() string { return fmt.Sprintf("footer can't be written: %v", e.Cause) }
+func (e *ErrFooterWrite) Unwrap() error { return e.Cause }
+
+// EntryKind is the type of an archive entry. Only regular files have payloads.
+type EntryKind uint8
+
+const (
+	KindFile EntryKind = iota
+	KindDir
+	KindSymlink
+	KindHardlink
+)
+
+func (k EntryKind) String() string {
+	switch k {
+	case KindFile:
+		return "file"
+	case KindDir:
+		return "dir"
+	case KindSymlink:
+		return "symlink"
+	case KindHardlink:
+		return "hardlink"
+	default:
+		return fmt.Sprintf("kind(%d)", uint8(k))
+	}
+}
+
+type File struct {
+	Path         string // relative path with the OS separator, stored with '/'
+	Checksum     string
+	Offset       int64
+	Size         int64
+	TrailingBits uint8 // used bits in the last payload byte, 0 if it is full
+	Codec        int   // index in Metadata.Codecs
+
+	Mode       uint32 // Unix permission bits (0o7777), 0 if unknown
+	ModTime    int64  // Unix time in nanoseconds, 0 if unknown
+	AccessTime int64
+	HasOwner   bool
+	Uid, Gid   int
+
+	Kind       EntryKind
+	LinkTarget string // symlink contents or archive path of the hard link target
+}
+
+// setAttributes копирует в запись режим, время и владельца файла
+func (f *File) setAttributes(info fs.FileInfo) {
+	f.Mode = utiles.UnixMode(info.Mode())
+	f.ModTime = info.ModTime().UnixNano()
+	f.AccessTime = utiles.AccessTime(info).UnixNano()
+	f.Uid, f.Gid, f.HasOwner = utiles.FileOwner(info)
+}
+
+// BitSize returns the payload size in bits.
+func (f *File) BitSize() int64 {
+	if f.Size == 0 || f.TrailingBits == 0 {
+		return f.Size * 8
+	}
+	return (f.Size-1)*8 + int64(f.TrailingBits)
+}
+
+// MixedType is the archive type when its entries use different codecs.
+const MixedType = "MIXED"
+
+// Codec identifies the compressor of archive entries.
+type Codec struct {
+	Type    string
+	Version int
+}
+
+type Metadata struct {
+	Type    string // compression type, MixedType if entries use different codecs
+	Version int    // codec format version, 0 for archives written before versioning
+	FileMap []File
+	Codecs  []Codec // footer bodies follow the metadata in the same order
+
+	format uint16 // archive format version, 0 for archives without header
+}
+
+// EntryCodecs returns the codecs of the archive. Archives written before
+// per-file codecs have one codec described by Type and Version.
+func (md *Metadata) EntryCodecs() []Codec {
+	if len(md.Codecs) == 0 && md.Type != "" {
+		return []Codec{{md.Type, md.Version}}
+	}
+	return md.Codecs
+}
+
+type Body any
+
+type Footer struct {
+	Metadata
+	Bodies []Body
+}
+
+func newFooter(codecs []Codec, fileMap []File, bodies []Body) *Footer {
+	md := Metadata{FileMap: fileMap, Codecs: codecs}
+	md.setType()
+	return &Footer{md, bodies}
+}
+
+// setType выводит Type и Version из списка кодеков. У архива без
+// обычных файлов кодеков нет и тип пустой
+func (md *Metadata) setType() {
+	md.Type, md.Version = "", 0
+	switch len(md.Codecs) {
+	case 0:
+	case 1:
+		md.Type, md.Version = md.Codecs[0].Type, md.Codecs[0].Version
+	default:
+		md.Type = MixedType
+	}
+}
+
+// Footer sections are written as the size (int64 LE) followed by the data.
+// Since FormatVersion 2 the data is in the binary format of Format.md,
+// older archives keep gob-encoded sections.
+const binaryFooterVersion = 2
+
+func writeSection(data []byte, file io.Writer) (size int64, err error) {
+	if err := binary.Write(file, binary.LittleEndian, int64(len(data))); err != nil {
+		return 0, err
+	}
+	if _, err := file.Write(data); err != nil {
+		return 0, err
+	}
+	return int64(len(data)) + 8, nil
+}
+
+func readSection(file io.Reader) (data []byte, size int64, err error) {
+	if err = binary.Read(file, binary.LittleEndian, &size); err != nil {
+		return nil, 0, err
+	}
+	if size < 0 {
+		return nil, 0, fmt.Errorf("invalid size: %d", size)
+	}
+	// размер может быть испорчен, поэтому память не выделяется заранее
+	var buf bytes.Buffer
+	if _, err = io.CopyN(&buf, file, size); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), size + 8, nil
+}
+
+// skipSection пропускает секцию футера, не читая ее данные
+func skipSection(file io.ReadSeeker) (size int64, err error) {
+	if err = binary.Read(file, binary.LittleEndian, &size); err != nil {
+		return 0, err
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("invalid size: %d", size)
+	}
+	if _, err = file.Seek(size, io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	return size + 8, nil
+}
+
+// BinaryBody is implemented by footer bodies. The encoding of every codec
+// body is described in Format.md. Method names differ from
+// encoding.BinaryMarshaler, so gob still decodes old footers field by field.
+type BinaryBody interface {
+	EncodeBody(e *Encoder)
+	DecodeBody(d *Decoder) error
+}
+
+// marshalBody кодирует тело футера. Для nil секция пустая
+func marshalBody(body Body) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	b, ok := body.(BinaryBody)
+	if !ok {
+		return nil, fmt.Errorf("footer body %T has no binary encoding", body)
+	}
+	e := NewEncoder()
+	b.EncodeBody(e)
+	return e.Bytes(), nil
+}
+
+func unmarshalBody(data []byte, body Body) error {
+	if body == nil {
+		if len(data) != 0 {
+			return fmt.Errorf("unexpected footer body of %d bytes", len(data))
+		}
+		return nil
+	}
+	b, ok := body.(BinaryBody)
+	if !ok {
+		return fmt.Errorf("footer body %T has no binary encoding", body)
+	}
+	d := NewDecoder(data)
+	if err := b.DecodeBody(d); err != nil {
+		return err
+	}
+	return d.Err()
+}
+
+// marshalMetadata кодирует метаданные и возвращает положение записей
+// файлов в результате для индекса
+func marshalMetadata(md *Metadata) ([]byte, []recordSpan) {
+	e := NewEncoder()
+	e.Uvarint(uint64(len(md.Codecs)))
+	for _, c := range md.Codecs {
+		e.String(c.Type)
+		e.Uvarint(uint64(c.Version))
+	}
+	e.Uvarint(uint64(len(md.FileMap)))
+	spans := make([]recordSpan, len(md.FileMap))
+	for i := range md.FileMap {
+		record := md.FileMap[i].marshal()
+		e.Blob(record)
+		spans[i] = recordSpan{len(e.Bytes()) - len(record), len(record)}
+	}
+	return e.Bytes(), spans
+}
+
+func unmarshalMetadata(data []byte) (*Metadata, error) {
+	md := &Metadata{}
+	d := NewDecoder(data)
+	md.Codecs = make([]Codec, d.Count(2))
+	for i := range md.Codecs {
+		md.Codecs[i] = Codec{d.String(), d.Int()}
+	}
+	md.FileMap = make([]File, d.Count(1))
+	for i := range md.FileMap {
+		record := d.Blob()
+		if d.Err() != nil {
+			break
+		}
+		if err := md.FileMap[i].unmarshal(record); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	md.setType()
+	return md, nil
+}
+
+func (f *File) marshal() []byte {
+	// пути в архиве разделяются '/' на любой ОС
+	e := NewEncoder()
+	e.String(filepath.ToSlash(f.Path))
+	e.String(f.Checksum)
+	e.Uvarint(uint64(f.Offset))
+	e.Uvarint(uint64(f.Size))
+	e.Byte(f.TrailingBits)
+	e.Uvarint(uint64(f.Codec))
+
+	e.Uvarint(uint64(f.Mode))
+	e.Varint(f.ModTime)
+	e.Varint(f.AccessTime)
+	if f.HasOwner {
+		e.Byte(1)
+		e.Uvarint(uint64(f.Uid))
+		e.Uvarint(uint64(f.Gid))
+	} else {
+		e.Byte(0)
+	}
+
+	e.Byte(byte(f.Kind))
+	e.String(filepath.ToSlash(f.LinkTarget))
+	return e.Bytes()
+}
+
+// unmarshal читает запись файла. Поля, добавленные в новых версиях,
+// дописываются в конец записи, поэтому лишние байты пропускаются
+func (f *File) unmarshal(record []byte) error {
+	d := NewDecoder(record)
+	f.Path = filepath.FromSlash(d.String())
+	f.Checksum = d.String()
+	f.Offset = int64(d.Int())
+	f.Size = int64(d.Int())
+	f.TrailingBits = d.Byte()
+	f.Codec = d.Int()
+	if d.Len() > 0 {
+		f.Mode = uint32(d.Uvarint())
+		f.ModTime = d.Varint()
+		f.AccessTime = d.Varint()
+		if f.HasOwner = d.Byte() == 1; f.HasOwner {
+			f.Uid, f.Gid = d.Int(), d.Int()
+		}
+	}
+	if d.Len() > 0 {
+		f.Kind = EntryKind(d.Byte())
+		f.LinkTarget = filepath.FromSlash(d.String())
+	}
+	if err := d.Err(); err != nil {
+		return fmt.Errorf("file record: %w", err)
+	}
+	if f.TrailingBits > 7 {
+		return fmt.Errorf("file record: invalid trailing bits %d", f.TrailingBits)
+	}
+	return nil
+}
+
+// writeFooter записывает индекс, метаданные и тела кодеков. start —
+// смещение футера в архиве, от него считаются смещения записей в индексе
+func writeFooter(footer *Footer, file io.Writer, start int64) (size int64, err error) {
+	metadata, spans := marshalMetadata(&footer.Metadata)
+	// размер индекса не зависит от смещений, поэтому их можно посчитать заранее
+	indexSize := int64(len(marshalIndex(&footer.Metadata, spans, 0, pathHash)))
+	index := marshalIndex(&footer.Metadata, spans, start+8+indexSize+8, pathHash)
+	if size, err = writeSection(index, file); err != nil {
+		return 0, &ErrFooterWrite{err}
+	}
+	mdSize, err := writeSection(metadata, file)
+	if err != nil {
+		return 0, &ErrFooterWrite{err}
+	}
+	size += mdSize
+	for _, body := range footer.Bodies {
+		data, err := marshalBody(body)
+		if err != nil {
+			return 0, &ErrFooterWrite{err}
+		}
+		bodySize, err := writeSection(data, file)
+		if err != nil {
+			return 0, &ErrFooterWrite{err}
+		}
+		size += bodySize
+	}
+	return size, nil
+}
+
+// readGob читает секцию футера архивов до FormatVersion 2
+func readGob(data []byte, dataType any) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if dataType == nil {
+		return fmt.Errorf("unexpected footer body of %d bytes", len(data))
+	}
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	gob.Register(dataType)
+	return dec.Decode(dataType)
+}
+
+func readFooterBody(file io.ReadSeeker, footerDataType Body, format uint16) (size int64, err error) {
+	data, size, err := readSection(file)
+	if err != nil {
+		return 0, err
+	}
+	if format < binaryFooterVersion {
+		err = readGob(data, footerDataType)
+	} else {
+		err = unmarshalBody(data, footerDataType)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// BodySize returns the number of bytes the body takes in the footer.
+func BodySize(body Body) (int64, error) {
+	data, err := marshalBody(body)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(data)) + 8, nil
+}
+
+func readFooterMetadata(file io.ReadSeeker, format uint16) (md *Metadata, size int64, err error) {
+	data, size, err := readSection(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	if format < binaryFooterVersion {
+		err = readGob(data, &md)
+	} else {
+		md, err = unmarshalMetadata(data)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if md == nil {
+		return nil, 0, fmt.Errorf("empty metadata")
+	}
+	md.format = format
+	return md, size, nil
+}
@@ -0,0 +1,171 @@
+package compressing
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testBody — тело кодека с полями всех видов кодировки
+type testBody struct {
+	Level  uint64
+	Name   string
+	Symbol []byte
+}
+
+func (b *testBody) EncodeBody(e *Encoder) {
+	e.Uvarint(b.Level)
+	e.String(b.Name)
+	e.Blob(b.Symbol)
+}
+
+func (b *testBody) DecodeBody(d *Decoder) error {
+	b.Level = d.Uvarint()
+	b.Name = d.String()
+	b.Symbol = d.Blob()
+	return d.Err()
+}
+
+func TestFooterRoundTrip(t *testing.T) {
+	checksum := strings.Repeat("0f", 32)
+	cases := []struct {
+		name     string
+		files    []File
+		codecs   []Codec
+		wantType string
+	}{
+		{"no files", nil, nil, ""},
+		{"one codec", []File{
+			{Path: "a.txt", Checksum: checksum, Offset: HeaderSize, Size: 5, TrailingBits: 3},
+			{Path: "empty", Checksum: checksum, Offset: HeaderSize + 5},
+		}, []Codec{{"HUFF", 1}}, "HUFF"},
+		{"mixed", []File{
+			{Path: "dir", Kind: KindDir, Mode: 0o755, ModTime: -1, AccessTime: 1 << 62},
+			{Path: "dir/a", Checksum: checksum, Offset: HeaderSize, Size: 1 << 40, Codec: 1, Mode: 0o4755,
+				HasOwner: true, Uid: 1000, Gid: 100},
+			{Path: "dir/link", Kind: KindSymlink, LinkTarget: "../outside"},
+			{Path: "hard", Kind: KindHardlink, LinkTarget: "dir/a", HasOwner: true},
+		}, []Codec{{"HUFF", 1}, {"STORE", 0}}, MixedType},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := writeArchive(t, c.files, c.codecs)
+			md, _, err := ReadFooterMetadata(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if md.Type != c.wantType || !slices.Equal(md.Codecs, c.codecs) {
+				t.Fatalf("got type %q and codecs %v, want %q and %v", md.Type, md.Codecs, c.wantType, c.codecs)
+			}
+			if !slices.Equal(md.FileMap, c.files) {
+				t.Fatalf("got files %+v, want %+v", md.FileMap, c.files)
+			}
+			// пустые тела кодеков идут за метаданными
+			for i := range c.codecs {
+				if _, err := readFooterBody(f, nil, md.format); err != nil {
+					t.Fatalf("body %d: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBodyRoundTrip(t *testing.T) {
+	cases := []*testBody{
+		{},
+		{Level: 9, Name: "level", Symbol: []byte{0, 1, 2}},
+		{Level: 1<<64 - 1, Name: strings.Repeat("x", 300), Symbol: bytes.Repeat([]byte{0xff}, 1000)},
+	}
+	for _, body := range cases {
+		data, err := marshalBody(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := &testBody{}
+		if err := unmarshalBody(data, got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Level != body.Level || got.Name != body.Name || !bytes.Equal(got.Symbol, body.Symbol) {
+			t.Fatalf("got %+v, want %+v", got, body)
+		}
+		// оборванное тело не читается
+		if len(data) > 0 {
+			if err := unmarshalBody(data[:len(data)-1], &testBody{}); err == nil {
+				t.Fatalf("truncated body %+v was read", body)
+			}
+		}
+	}
+}
+
+func TestFileRecordCompat(t *testing.T) {
+	full := File{
+		Path: "a", Checksum: "sum", Offset: 8, Size: 3, TrailingBits: 5, Codec: 2,
+		Mode: 0o600, ModTime: 10, AccessTime: 20, HasOwner: true, Uid: 1, Gid: 2,
+		Kind: KindSymlink, LinkTarget: "b",
+	}
+	// запись первой двоичной версии: без атрибутов и вида записи
+	e := NewEncoder()
+	e.String(full.Path)
+	e.String(full.Checksum)
+	e.Uvarint(uint64(full.Offset))
+	e.Uvarint(uint64(full.Size))
+	e.Byte(full.TrailingBits)
+	e.Uvarint(uint64(full.Codec))
+	old := e.Bytes()
+
+	cases := []struct {
+		name    string
+		record  []byte
+		want    File
+		wantErr bool
+	}{
+		{"current", full.marshal(), full, false},
+		{"old", old, File{Path: "a", Checksum: "sum", Offset: 8, Size: 3, TrailingBits: 5, Codec: 2}, false},
+		{"newer fields", append(full.marshal(), 1, 2, 3), full, false},
+		{"truncated", full.marshal()[:len(old)-1], File{}, true},
+		{"trailing bits", func() []byte {
+			f := full
+			f.TrailingBits = 8
+			return f.marshal()
+		}(), File{}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var f File
+			err := f.unmarshal(c.record)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", f)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f != c.want {
+				t.Fatalf("got %+v, want %+v", f, c.want)
+			}
+		})
+	}
+}
+
+func TestFileRecordSeparator(t *testing.T) {
+	f := File{Path: filepath.Join("dir", "sub", "a"), Kind: KindHardlink, LinkTarget: filepath.Join("dir", "b")}
+	record := f.marshal()
+	if path := NewDecoder(record).String(); path != "dir/sub/a" {
+		t.Fatalf("got path %q in the record, want dir/sub/a", path)
+	}
+	if !bytes.HasSuffix(record, []byte("dir/b")) {
+		t.Fatalf("link target of the record %q isn't dir/b", record)
+	}
+
+	var got File
+	if err := got.unmarshal(record); err != nil {
+		t.Fatal(err)
+	}
+	if got.Path != f.Path || got.LinkTarget != f.LinkTarget {
+		t.Fatalf("got %s -> %s, want %s -> %s", got.Path, got.LinkTarget, f.Path, f.LinkTarget)
+	}
+}
@@ -0,0 +1,105 @@
+package compressing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Archive layout:
+//
+//	header | payloads | footer | footer size (int64 LE)
+//
+// Header is HeaderSize bytes: Magic, format version (uint16 LE)
+// and feature flags (uint16 LE). Archives written before the header
+// start directly with the payloads.
+const (
+	Magic         = "DEDL"
+	FormatVersion = 3
+	HeaderSize    = int64(len(Magic) + 4)
+)
+
+// Flags are archive features a reader must support.
+type Flags uint16
+
+// FlagStream marks archives written sequentially: codec bodies and a local
+// record of every entry precede the payloads, so the archive can be read
+// from a pipe. The footer is the same as in other archives.
+const FlagStream Flags = 1 << 0
+
+// knownFlags — флаги, которые понимает эта версия
+const knownFlags = FlagStream
+
+type Header struct {
+	Version uint16
+	Flags   Flags
+}
+
+type ErrNotArchive struct{ Cause error }
+
+func (e *ErrNotArchive) Error() string {
+	if e.Cause == nil {
+		return "not a dedal archive"
+	}
+	return fmt.Sprintf("not a dedal archive: %v", e.Cause)
+}
+func (e *ErrNotArchive) Unwrap() error { return e.Cause }
+
+type ErrUnsupportedVersion struct{ Version uint16 }
+
+func (e *ErrUnsupportedVersion) Error() string {
+	return fmt.Sprintf("unsupported version %d", e.Version)
+}
+
+type ErrUnsupportedFlags struct{ Flags Flags }
+
+func (e *ErrUnsupportedFlags) Error() string {
+	return fmt.Sprintf("unsupported archive features: %#04x", uint16(e.Flags))
+}
+
+func newHeader(flags Flags) *Header { return &Header{FormatVersion, flags} }
+
+func writeHeader(h *Header, w io.Writer) error {
+	buf := make([]byte, 0, HeaderSize)
+	buf = append(buf, Magic...)
+	buf = binary.LittleEndian.AppendUint16(buf, h.Version)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(h.Flags))
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadHeader reads and validates the header at the start of the archive.
+// ErrNotArchive is returned if there is no magic.
+func ReadHeader(r io.ReadSeeker) (*Header, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return readHeader(r)
+}
+
+// readHeader читает заголовок с текущей позиции, r может быть каналом
+func readHeader(r io.Reader) (*Header, error) {
+	buf := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, &ErrNotArchive{}
+		}
+		return nil, err
+	}
+	if !bytes.Equal(buf[:len(Magic)], []byte(Magic)) {
+		return nil, &ErrNotArchive{}
+	}
+
+	h := &Header{
+		Version: binary.LittleEndian.Uint16(buf[len(Magic):]),
+		Flags:   Flags(binary.LittleEndian.Uint16(buf[len(Magic)+2:])),
+	}
+	if h.Version == 0 || h.Version > FormatVersion {
+		return nil, &ErrUnsupportedVersion{h.Version}
+	}
+	if h.Flags&^knownFlags != 0 {
+		return nil, &ErrUnsupportedFlags{h.Flags &^ knownFlags}
+	}
+	return h, nil
+}
@@ -0,0 +1,286 @@
+package compressing
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Since FormatVersion 3 the footer starts with the entry index: fixed-size
+// records of the entries sorted by path and a hash table of paths, so one
+// entry can be found without reading the whole metadata. See Format.md.
+const indexFooterVersion = 3
+
+const (
+	indexHeaderSize = 8
+	indexRecordSize = 24
+	indexSlotSize   = 4
+)
+
+// ErrNoIndex is returned by OpenIndex for archives written before the index.
+var ErrNoIndex = errors.New("archive has no entry index")
+
+// recordSpan — положение записи файла внутри секции метаданных
+type recordSpan struct{ pos, size int }
+
+type indexRecord struct {
+	hash   uint64
+	offset int64
+	size   int64
+}
+
+// pathHash — FNV-1a пути записи с разделителями '/'
+func pathHash(path string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(filepath.ToSlash(path)))
+	return h.Sum64()
+}
+
+// indexSlots возвращает размер хэш-таблицы: степень двойки, не меньше
+// удвоенного числа записей, чтобы цепочки проб оставались короткими
+func indexSlots(count int) int {
+	if count == 0 {
+		return 0
+	}
+	slots := 1
+	for slots < 2*count {
+		slots <<= 1
+	}
+	return slots
+}
+
+// marshalIndex кодирует индекс. mdStart — абсолютное смещение данных
+// секции метаданных, spans — положение записей файлов в ней, hash —
+// хэш путей (pathHash, тесты подставляют свой)
+func marshalIndex(md *Metadata, spans []recordSpan, mdStart int64, hash func(string) uint64) []byte {
+	count, slots := len(md.FileMap), indexSlots(len(md.FileMap))
+	order := make([]int, count)
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return strings.Compare(filepath.ToSlash(md.FileMap[a].Path), filepath.ToSlash(md.FileMap[b].Path))
+	})
+
+	buf := make([]byte, indexHeaderSize+count*indexRecordSize+slots*indexSlotSize)
+	binary.LittleEndian.PutUint32(buf, uint32(count))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(slots))
+	table := buf[indexHeaderSize+count*indexRecordSize:]
+	for k, i := range order {
+		h := hash(md.FileMap[i].Path)
+		rec := buf[indexHeaderSize+k*indexRecordSize:]
+		binary.LittleEndian.PutUint64(rec, h)
+		binary.LittleEndian.PutUint64(rec[8:], uint64(mdStart)+uint64(spans[i].pos))
+		binary.LittleEndian.PutUint64(rec[16:], uint64(spans[i].size))
+
+		slot := int(h & uint64(slots-1))
+		for binary.LittleEndian.Uint32(table[slot*indexSlotSize:]) != 0 {
+			slot = (slot + 1) & (slots - 1)
+		}
+		binary.LittleEndian.PutUint32(table[slot*indexSlotSize:], uint32(k+1))
+	}
+
+	// копия списка кодеков, чтобы читать тела без метаданных
+	e := NewEncoder()
+	e.Uvarint(uint64(len(md.Codecs)))
+	for _, c := range md.Codecs {
+		e.String(c.Type)
+		e.Uvarint(uint64(c.Version))
+	}
+	return append(buf, e.Bytes()...)
+}
+
+// Index gives random access to the entries of an archive: a lookup reads
+// only a few fixed-size records and the records of the matching entries.
+type Index struct {
+	Codecs []Codec
+
+	file    *os.File
+	hash    func(string) uint64
+	format  uint16
+	count   int
+	slots   int
+	records int64 // смещение отсортированных записей
+	end     int64 // конец секции индекса, начало секции метаданных
+	limit   int64 // конец секций футера
+}
+
+// OpenIndex reads the header of the entry index. ErrNoIndex is returned
+// for archives of older formats, they have to be read with ReadFooterMetadata.
+func OpenIndex(file *os.File) (*Index, error) {
+	header, err := ReadHeader(file)
+	var notArchive *ErrNotArchive
+	if errors.As(err, &notArchive) {
+		return nil, ErrNoIndex
+	} else if err != nil {
+		return nil, err
+	}
+	if header.Version < indexFooterVersion {
+		return nil, ErrNoIndex
+	}
+
+	ix, err := readIndex(file)
+	if err != nil {
+		return nil, &ErrFooterRead{fmt.Errorf("index: %w", err)}
+	}
+	ix.format = header.Version
+	return ix, nil
+}
+
+func readIndex(file *os.File) (*Index, error) {
+	end, err := file.Seek(-8, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	var buf [16]byte
+	if _, err := file.ReadAt(buf[:8], end); err != nil {
+		return nil, err
+	}
+	footerSize := int64(binary.LittleEndian.Uint64(buf[:]))
+	if footerSize <= 0 || footerSize > end-HeaderSize {
+		return nil, fmt.Errorf("invalid footer size: %d", footerSize)
+	}
+	start := end - footerSize
+	if _, err := file.ReadAt(buf[:], start); err != nil {
+		return nil, err
+	}
+	size := int64(binary.LittleEndian.Uint64(buf[:]))
+	ix := &Index{
+		file:    file,
+		hash:    pathHash,
+		count:   int(binary.LittleEndian.Uint32(buf[8:])),
+		slots:   int(binary.LittleEndian.Uint32(buf[12:])),
+		records: start + 8 + indexHeaderSize,
+		end:     start + 8 + size,
+		limit:   end,
+	}
+	tableEnd := int64(indexHeaderSize) + int64(ix.count)*indexRecordSize + int64(ix.slots)*indexSlotSize
+	if size < tableEnd || size > footerSize-8 || ix.slots != indexSlots(ix.count) {
+		return nil, fmt.Errorf("invalid index size")
+	}
+
+	codecs := make([]byte, size-tableEnd)
+	if _, err := file.ReadAt(codecs, start+8+tableEnd); err != nil {
+		return nil, err
+	}
+	d := NewDecoder(codecs)
+	ix.Codecs = make([]Codec, d.Count(2))
+	for i := range ix.Codecs {
+		ix.Codecs[i] = Codec{d.String(), d.Int()}
+	}
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Lookup returns the entry with the path. ErrNoMatch is returned if
+// there is no such entry.
+func (ix *Index) Lookup(path string) (*File, error) {
+	path = filepath.Clean(path)
+	hash := ix.hash(path)
+	mask := uint64(ix.slots - 1)
+	for probe, slot := 0, hash&mask; probe < ix.slots; probe, slot = probe+1, (slot+1)&mask {
+		var buf [indexSlotSize]byte
+		if _, err := ix.file.ReadAt(buf[:], ix.records+int64(ix.count)*indexRecordSize+int64(slot)*indexSlotSize); err != nil {
+			return nil, err
+		}
+		k := int(binary.LittleEndian.Uint32(buf[:]))
+		if k == 0 {
+			break
+		}
+		if k > ix.count {
+			return nil, fmt.Errorf("invalid index slot %d", k)
+		}
+		rec, err := ix.record(k - 1)
+		if err != nil {
+			return nil, err
+		}
+		if rec.hash != hash {
+			continue
+		}
+		f, err := ix.readFile(rec)
+		if err != nil {
+			return nil, err
+		}
+		if f.Path == path {
+			return f, nil
+		}
+	}
+	return nil, &ErrNoMatch{path}
+}
+
+// List returns the entries inside the directory dir, in path order.
+// The start of the directory is found by a binary search.
+func (ix *Index) List(dir string) ([]File, error) {
+	// записи отсортированы по путям с разделителями '/'
+	prefix := filepath.ToSlash(filepath.Clean(dir)) + "/"
+	var searchErr error
+	first := sort.Search(ix.count, func(k int) bool {
+		f, err := ix.entry(k)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return filepath.ToSlash(f.Path) >= prefix
+	})
+	if searchErr != nil {
+		return nil, searchErr
+	}
+
+	var files []File
+	for k := first; k < ix.count; k++ {
+		f, err := ix.entry(k)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(filepath.ToSlash(f.Path), prefix) {
+			break
+		}
+		files = append(files, *f)
+	}
+	return files, nil
+}
+
+func (ix *Index) entry(k int) (*File, error) {
+	rec, err := ix.record(k)
+	if err != nil {
+		return nil, err
+	}
+	return ix.readFile(rec)
+}
+
+func (ix *Index) record(k int) (*indexRecord, error) {
+	var buf [indexRecordSize]byte
+	if _, err := ix.file.ReadAt(buf[:], ix.records+int64(k)*indexRecordSize); err != nil {
+		return nil, err
+	}
+	rec := &indexRecord{
+		hash:   binary.LittleEndian.Uint64(buf[:]),
+		offset: int64(binary.LittleEndian.Uint64(buf[8:])),
+		size:   int64(binary.LittleEndian.Uint64(buf[16:])),
+	}
+	if rec.offset < ix.end || rec.size < 0 || rec.size > ix.limit-rec.offset {
+		return nil, fmt.Errorf("invalid index record %d", k)
+	}
+	return rec, nil
+}
+
+func (ix *Index) readFile(rec *indexRecord) (*File, error) {
+	buf := make([]byte, rec.size)
+	if _, err := ix.file.ReadAt(buf, rec.offset); err != nil {
+		return nil, err
+	}
+	f := &File{}
+	if err := f.unmarshal(buf); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,236 @@
+package compressing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeArchive записывает архив без данных файлов: заголовок и футер с
+// записями files и пустыми телами кодеков
+func writeArchive(t *testing.T, files []File, codecs []Codec) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "index.dedal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	if err := writeHeader(newHeader(0), f); err != nil {
+		t.Fatal(err)
+	}
+	bodies := make([]Body, len(codecs))
+	if _, err := finishArchive(newFooter(codecs, files, bodies), f, HeaderSize); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// rewriteIndex перезаписывает индекс архива, построенный с хэшем hash.
+// Размер индекса от хэша не зависит, поэтому секция пишется на место старой
+func rewriteIndex(t *testing.T, ix *Index, files []File, codecs []Codec, hash func(string) uint64) {
+	t.Helper()
+	md := &newFooter(codecs, files, nil).Metadata
+	_, spans := marshalMetadata(md)
+	index := marshalIndex(md, spans, ix.end+8, hash)
+	if _, err := ix.file.WriteAt(index, ix.records-indexHeaderSize); err != nil {
+		t.Fatal(err)
+	}
+	ix.hash = hash
+}
+
+func testEntries(pathes ...string) []File {
+	files := make([]File, len(pathes))
+	for i, path := range pathes {
+		files[i] = File{
+			Path:     filepath.FromSlash(path),
+			Checksum: strings.Repeat("ab", 32),
+			Offset:   HeaderSize + int64(i)*10,
+			Size:     10,
+			Mode:     0o644,
+			ModTime:  int64(i) * 1e9,
+		}
+	}
+	return files
+}
+
+func entryPathes(files []File) []string {
+	pathes := make([]string, len(files))
+	for i, f := range files {
+		pathes[i] = filepath.ToSlash(f.Path)
+	}
+	return pathes
+}
+
+func TestIndexRoundTrip(t *testing.T) {
+	cases := []struct {
+		name   string
+		files  []File
+		codecs []Codec
+	}{
+		{"empty", nil, nil},
+		{"one", testEntries("a.txt"), []Codec{{"HUFF", 1}}},
+		{"unsorted", testEntries("z", "b/c", "a", "b"), []Codec{{"HUFF", 1}, {"STORE", 0}}},
+		{"many", testEntries(strings.Split("q w e r t y u i o p a s d f g h j k l", " ")...), []Codec{{"LZSS", 0}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ix, err := OpenIndex(writeArchive(t, c.files, c.codecs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ix.count != len(c.files) || ix.slots != indexSlots(len(c.files)) {
+				t.Fatalf("got %d entries and %d slots, want %d and %d",
+					ix.count, ix.slots, len(c.files), indexSlots(len(c.files)))
+			}
+			if !slices.Equal(ix.Codecs, c.codecs) {
+				t.Fatalf("got codecs %v, want %v", ix.Codecs, c.codecs)
+			}
+
+			// записи индекса отсортированы по путям и указывают на записи файлов
+			want := slices.Clone(c.files)
+			slices.SortFunc(want, func(a, b File) int { return strings.Compare(a.Path, b.Path) })
+			for k := range want {
+				f, err := ix.entry(k)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if *f != want[k] {
+					t.Fatalf("entry %d: got %+v, want %+v", k, *f, want[k])
+				}
+			}
+		})
+	}
+}
+
+func TestIndexLookup(t *testing.T) {
+	files := testEntries("a.txt", "dir/b.txt", "dir/sub/c.txt", "dir2/d.txt")
+	cases := []struct {
+		name    string
+		collide bool // все пути получают один хэш
+		path    string
+		found   bool
+	}{
+		{"file", false, "a.txt", true},
+		{"nested", false, "dir/sub/c.txt", true},
+		{"unclean", false, "dir/./sub//c.txt", true},
+		{"missing", false, "dir/c.txt", false},
+		{"prefix of a path", false, "dir/sub", false},
+		{"collision", true, "dir/b.txt", true},
+		{"collision last", true, "dir2/d.txt", true},
+		{"collision missing", true, "missing.txt", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := writeArchive(t, files, []Codec{{"HUFF", 1}})
+			ix, err := OpenIndex(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.collide {
+				rewriteIndex(t, ix, files, []Codec{{"HUFF", 1}}, func(string) uint64 { return 7 })
+			}
+
+			entry, err := ix.Lookup(filepath.FromSlash(c.path))
+			if !c.found {
+				var noMatch *ErrNoMatch
+				if !errors.As(err, &noMatch) {
+					t.Fatalf("got %v, %v, want ErrNoMatch", entry, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Clean(filepath.FromSlash(c.path)); entry.Path != want {
+				t.Fatalf("got %s, want %s", entry.Path, want)
+			}
+		})
+	}
+}
+
+func TestIndexList(t *testing.T) {
+	// '-' и '.' меньше '/', поэтому такие пути лежат между "dir" и "dir/..."
+	files := testEntries(
+		"dir", "dir-x/f", "dir.txt", "dir/a", "dir/sub", "dir/sub/b", "dir2/c", "other/dir/d",
+	)
+	cases := []struct {
+		dir  string
+		want []string
+	}{
+		{"dir", []string{"dir/a", "dir/sub", "dir/sub/b"}},
+		{"dir/", []string{"dir/a", "dir/sub", "dir/sub/b"}},
+		{"dir/sub", []string{"dir/sub/b"}},
+		{"dir2", []string{"dir2/c"}},
+		{"other", []string{"other/dir/d"}},
+		{"di", nil},
+		{"dir/a", nil},
+		{"missing", nil},
+	}
+	ix, err := OpenIndex(writeArchive(t, files, []Codec{{"STORE", 0}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		t.Run(c.dir, func(t *testing.T) {
+			got, err := ix.List(filepath.FromSlash(c.dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(entryPathes(got), c.want) {
+				t.Fatalf("got %v, want %v", entryPathes(got), c.want)
+			}
+		})
+	}
+}
+
+func TestIndexBounds(t *testing.T) {
+	files := testEntries("a", "b", "c")
+	cases := []struct {
+		name    string
+		corrupt func(ix []byte)
+	}{
+		// смещение записи до секции метаданных
+		{"record offset", func(ix []byte) { clear(ix[indexHeaderSize+8 : indexHeaderSize+16]) }},
+		{"record size", func(ix []byte) { ix[indexHeaderSize+23] = 0x7f }},
+		{"slot", func(ix []byte) {
+			table := ix[indexHeaderSize+3*indexRecordSize:]
+			for i := 0; i < indexSlots(3); i++ {
+				if table[i*indexSlotSize] != 0 {
+					table[i*indexSlotSize] = 200
+				}
+			}
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := writeArchive(t, files, []Codec{{"STORE", 0}})
+			ix, err := OpenIndex(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// секция индекса начинается за ее размером
+			buf := make([]byte, ix.end-(ix.records-indexHeaderSize))
+			if _, err := f.ReadAt(buf, ix.records-indexHeaderSize); err != nil {
+				t.Fatal(err)
+			}
+			c.corrupt(buf)
+			if _, err := f.WriteAt(buf, ix.records-indexHeaderSize); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, path := range []string{"a", "b", "c"} {
+				if _, err := ix.Lookup(path); err == nil {
+					continue
+				} else if errors.As(err, new(*ErrNoMatch)) {
+					t.Fatalf("%s: got %v, want an index error", path, err)
+				}
+				return
+			}
+			t.Fatal("corrupted index was read without errors")
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package compressing
+
+import (
+	"compressor/internal/utiles"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ErrEntryExists is returned when an added file has the path of an entry
+// of the archive.
+type ErrEntryExists struct{ Path string }
+
+func (e *ErrEntryExists) Error() string { return fmt.Sprintf("%s is already in the archive", e.Path) }
+
+// archiveContent — записи, кодеки и тела кодеков архива, который изменяется
+type archiveContent struct {
+	fileMap     []File
+	codecs      []Codec
+	bodies      []Body
+	version     uint16
+	flags       Flags
+	footerStart int64 // конец данных файлов
+}
+
+// readArchive читает футер архива целиком. Тела кодеков декодируются,
+// чтобы записать их в текущем формате.
+func readArchive(factory DecompressorFactory, file *os.File) (*archiveContent, error) {
+	// у архивов без заголовка version остается 0
+	version, flags := uint16(0), Flags(0)
+	header, err := ReadHeader(file)
+	var notArchive *ErrNotArchive
+	if err == nil {
+		version, flags = header.Version, header.Flags
+	} else if !errors.As(err, &notArchive) {
+		return nil, err
+	}
+	md, _, err := ReadFooterMetadata(file)
+	if err != nil {
+		return nil, err
+	}
+
+	content := &archiveContent{fileMap: md.FileMap, codecs: md.EntryCodecs(), version: version, flags: flags}
+	content.bodies = make([]Body, len(content.codecs))
+	for i, codec := range content.codecs {
+		decomp := factory(codec.Type, codec.Version)
+		if decomp == nil {
+			return nil, fmt.Errorf("unsupported compression type: %s", codec.Type)
+		}
+		content.bodies[i] = decomp.FooterBodyType()
+		if _, err := readFooterBody(file, content.bodies[i], md.format); err != nil {
+			return nil, &ErrFooterRead{err}
+		}
+		if _, err := marshalBody(content.bodies[i]); err != nil {
+			return nil, fmt.Errorf("codec %s version %d can't be written in the current format: %w", codec.Type, codec.Version, err)
+		}
+	}
+
+	end, err := file.Seek(-8, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	var footerSize int64
+	if err := binary.Read(file, binary.LittleEndian, &footerSize); err != nil {
+		return nil, err
+	}
+	content.footerStart = end - footerSize
+	return content, nil
+}
+
+// AppendFiles compresses the files and adds them to the archive after its
+// last payload, then rewrites the footer with the old and the new entries.
+// comps and fallback are used as by CompressFilesWith. The new files get
+// codecs of their own, so a model such as the Huffman table of the old
+// entries never has to cover the new data. If compression fails, the old
+// footer is written back; an error of restoring it is wrapped together
+// with the original error. The archive is modified in place, so a crash
+// while the files are written leaves it unreadable.
+func AppendFiles(
+	factory DecompressorFactory, comps []CompressionBase, fallback FastCompressor,
+	pathes []string, archive *os.File, prog *utiles.Progress[int64],
+) (contentSize int64, footerSize int64, err error) {
+	if len(comps) != len(pathes) {
+		return 0, 0, fmt.Errorf("got %d compressors for %d files", len(comps), len(pathes))
+	}
+	old, err := readArchive(factory, archive)
+	if err != nil {
+		return 0, 0, err
+	}
+	if old.version == 0 {
+		// данные таких архивов начинаются с начала файла, заголовок не вставить
+		return 0, 0, fmt.Errorf("archives without a header can't be appended to, compress the files again")
+	}
+	fileMap, err := newEntries(pathes)
+	if err != nil {
+		return 0, 0, err
+	}
+	comps, pathes, fileMap, err = newOnly(old.fileMap, comps, pathes, fileMap)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// новые данные пишутся на место старого футера, он сохраняется,
+	// чтобы вернуть архив в прежнее состояние при ошибке
+	start := old.footerStart
+	oldFooter, err := io.ReadAll(io.NewSectionReader(archive, start, 1<<62))
+	if err != nil {
+		return 0, 0, err
+	}
+	restore := func(cause error) error {
+		// при прерывании файл закрывается, поэтому он открывается заново
+		if err := restoreFooter(archive.Name(), start, oldFooter); err != nil {
+			return fmt.Errorf("%w; the old footer can't be written back: %w", cause, err)
+		}
+		return cause
+	}
+	if err := archive.Truncate(start); err != nil {
+		return 0, 0, restore(err)
+	}
+	if _, err := archive.Seek(start, io.SeekStart); err != nil {
+		return 0, 0, restore(err)
+	}
+
+	codecs, bodies, contentSize, err := compressEntries(comps, fallback, pathes, fileMap, archive, start, len(old.codecs), prog)
+	if err != nil {
+		return 0, 0, restore(err)
+	}
+	footer := newFooter(
+		append(old.codecs, codecs...), append(old.fileMap, fileMap...), append(old.bodies, bodies...),
+	)
+	if footerSize, err = finishArchive(footer, archive, contentSize); err != nil {
+		return 0, 0, restore(err)
+	}
+
+	// футер уже в текущем формате, заголовок меняется последним. У новых
+	// файлов нет локальных записей, поэтому архив перестает быть потоковым
+	if old.version < FormatVersion || old.flags&FlagStream != 0 {
+		if err := writeHeader(newHeader(0), io.NewOffsetWriter(archive, 0)); err != nil {
+			return 0, 0, restore(&ErrCompression{err})
+		}
+	}
+	return contentSize, footerSize, nil
+}
+
+// restoreFooter возвращает на место футер архива path, начинавшийся с
+// позиции start, и сбрасывает файл на диск
+func restoreFooter(path string, start int64, footer []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Truncate(start); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(footer, start); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// newOnly убирает из добавляемых записей каталоги, которые уже есть в
+// архиве. Остальные записи с путями из архива не допускаются.
+func newOnly(
+	old []File, comps []CompressionBase, pathes []string, fileMap []File,
+) ([]CompressionBase, []string, []File, error) {
+	existing := make(map[string]EntryKind, len(old))
+	for _, f := range old {
+		existing[filepath.Clean(f.Path)] = f.Kind
+	}
+	var keep []int
+	for i, f := range fileMap {
+		kind, ok := existing[filepath.Clean(f.Path)]
+		if !ok {
+			keep = append(keep, i)
+		} else if kind != KindDir || f.Kind != KindDir {
+			return nil, nil, nil, &ErrEntryExists{f.Path}
+		}
+	}
+	if len(keep) == len(fileMap) {
+		return comps, pathes, fileMap, nil
+	}
+	newComps, newPathes, newFileMap := make([]CompressionBase, len(keep)), make([]string, len(keep)), make([]File, len(keep))
+	for k, i := range keep {
+		newComps[k], newPathes[k], newFileMap[k] = comps[i], pathes[i], fileMap[i]
+	}
+	return newComps, newPathes, newFileMap, nil
+}
+
+// RemoveEntries writes the archive src to dst without the entries matching
+// the patterns, which are interpreted as by DecompressOptions.Exclude.
+// Payloads of the remaining entries are copied without recompression.
+// removed are the paths of the removed entries.
+func RemoveEntries(
+	factory DecompressorFactory, src *os.File, patterns []string, dst *os.File,
+) (removed []string, contentSize int64, footerSize int64, err error) {
+	old, err := readArchive(factory, src)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	matched := make([]bool, len(patterns))
+	keep := make([]bool, len(old.fileMap))
+	for i, e := range old.fileMap {
+		found, err := matchEntry(patterns, e.Path, matched)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		keep[i] = !found
+		if found {
+			removed = append(removed, e.Path)
+		}
+	}
+	for j, p := range patterns {
+		if !matched[j] {
+			return nil, 0, 0, &ErrNoMatch{p}
+		}
+	}
+
+	contentSize, footerSize, err = rebuildArchive(old, keepEntries(old.fileMap, keep), src, dst, nil)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return removed, contentSize, footerSize, nil
+}
+
+// UpdateFiles writes the archive src to dst with the files compressed
+// anew: entries with the same paths are replaced, the other files are
+// added. comps, fallback and the entry paths are as for AppendFiles.
+// Payloads of the remaining entries are copied without recompression.
+func UpdateFiles(
+	factory DecompressorFactory, comps []CompressionBase, fallback FastCompressor,
+	pathes []string, src *os.File, dst *os.File, prog *utiles.Progress[int64],
+) (contentSize int64, footerSize int64, err error) {
+	if len(comps) != len(pathes) {
+		return 0, 0, fmt.Errorf("got %d compressors for %d files", len(comps), len(pathes))
+	}
+	old, err := readArchive(factory, src)
+	if err != nil {
+		return 0, 0, err
+	}
+	fileMap, err := newEntries(pathes)
+	if err != nil {
+		return 0, 0, err
+	}
+	replaced := make(map[string]bool, len(fileMap))
+	for _, f := range fileMap {
+		replaced[filepath.Clean(f.Path)] = true
+	}
+	keep := make([]bool, len(old.fileMap))
+	for i, e := range old.fileMap {
+		keep[i] = !replaced[filepath.Clean(e.Path)]
+	}
+
+	return rebuildArchive(old, keepEntries(old.fileMap, keep), src, dst, &appended{comps, fallback, pathes, fileMap, prog})
+}
+
+// appended — файлы, которые сжимаются после скопированных записей
+type appended struct {
+	comps    []CompressionBase
+	fallback FastCompressor
+	pathes   []string
+	fileMap  []File
+	prog     *utiles.Progress[int64]
+}
+
+// rebuildArchive записывает в dst новый архив: записи entries старого
+// архива, затем сжатые файлы added, если они есть
+func rebuildArchive(
+	old *archiveContent, entries []File, src *os.File, dst *os.File, added *appended,
+) (contentSize int64, footerSize int64, err error) {
+	if err := writeHeader(newHeader(0), dst); err != nil {
+		return 0, 0, &ErrCompression{err}
+	}
+	fileMap, codecs, bodies, contentSize, err := copyEntries(old, entries, src, dst, HeaderSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	if added != nil {
+		newCodecs, newBodies, end, err := compressEntries(
+			added.comps, added.fallback, added.pathes, added.fileMap, dst, contentSize, len(codecs), added.prog,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+		fileMap = append(fileMap, added.fileMap...)
+		codecs, bodies, contentSize = append(codecs, newCodecs...), append(bodies, newBodies...), end
+	}
+
+	footerSize, err = finishArchive(newFooter(codecs, fileMap, bodies), dst, contentSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	return contentSize, footerSize, nil
+}
+
+// copyEntries копирует данные записей из архива src в dst с позиции offset
+// без перекодирования. Остаются только кодеки, на которые ссылаются
+// записи. Копии одного файла, оставшиеся от жестких ссылок, снова
+// становятся ссылками на первую из них.
+func copyEntries(
+	old *archiveContent, entries []File, src *os.File, dst *os.File, offset int64,
+) (fileMap []File, codecs []Codec, bodies []Body, end int64, err error) {
+	if _, err := dst.Seek(offset, io.SeekStart); err != nil {
+		return nil, nil, nil, 0, err
+	}
+	type payload struct{ offset, size int64 }
+	var (
+		renumber = make(map[int]int)
+		copied   = make(map[payload]string)
+	)
+	fileMap = slices.Clone(entries)
+	end = offset
+	for i := range fileMap {
+		f := &fileMap[i]
+		if f.Kind != KindFile {
+			continue
+		}
+		if f.Codec < 0 || f.Codec >= len(old.codecs) {
+			return nil, nil, nil, 0, fmt.Errorf("%s: invalid codec %d", f.Path, f.Codec)
+		}
+		if f.Size > 0 {
+			p := payload{f.Offset, f.Size}
+			if target, ok := copied[p]; ok {
+				*f = File{
+					Path: f.Path, Mode: f.Mode, ModTime: f.ModTime, AccessTime: f.AccessTime,
+					HasOwner: f.HasOwner, Uid: f.Uid, Gid: f.Gid, Kind: KindHardlink, LinkTarget: target,
+				}
+				continue
+			}
+			copied[p] = f.Path
+		}
+
+		codec, ok := renumber[f.Codec]
+		if !ok {
+			codec = len(codecs)
+			renumber[f.Codec] = codec
+			codecs = append(codecs, old.codecs[f.Codec])
+			bodies = append(bodies, old.bodies[f.Codec])
+		}
+		n, err := io.Copy(dst, io.NewSectionReader(src, f.Offset, f.Size))
+		if err == nil && n != f.Size {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, nil, nil, 0, &ErrCompression{fmt.Errorf("%s: %w", f.Path, err)}
+		}
+		f.Codec, f.Offset = codec, end
+		end += f.Size
+	}
+	return fileMap, codecs, bodies, end, nil
+}
@@ -0,0 +1,73 @@
+package compressing
+
+import (
+	"bytes"
+	"compressor/internal/utiles"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var errCompress = errors.New("compression failed")
+
+// failingCompressor пишет часть данных, вызывает fail и возвращает ошибку
+type failingCompressor struct{ fail func() }
+
+func (*failingCompressor) Preprocessing(_ []io.Reader) error { return nil }
+func (*failingCompressor) CompressorData() (string, Body)    { return "FAIL", nil }
+func (c *failingCompressor) CompressFile(src io.Reader, dst io.Writer, _ *utiles.Progress[int64]) (int64, uint8, error) {
+	n, _ := io.CopyN(dst, src, 3)
+	c.fail()
+	return n, 0, errCompress
+}
+
+func TestAppendFilesRestore(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "new.txt")
+	if err := os.WriteFile(src, []byte("data of the new file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name      string
+		removeArc bool // архив удаляется, и футер некуда вернуть
+	}{
+		{"restored", false},
+		{"restore fails", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			archive := writeArchive(t, nil, nil)
+			before, err := os.ReadFile(archive.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			comp := &failingCompressor{func() {
+				if c.removeArc {
+					os.Remove(archive.Name())
+				}
+			}}
+			prog := utiles.NewProgress[int64](0)
+			prog.Close()
+
+			_, _, err = AppendFiles(nil, []CompressionBase{comp}, nil, []string{src}, archive, prog)
+			if !errors.Is(err, errCompress) {
+				t.Fatalf("got %v, want the compression error", err)
+			}
+			if c.removeArc {
+				if !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("got %v, want the restore error too", err)
+				}
+				return
+			}
+			after, err := os.ReadFile(archive.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(after, before) {
+				t.Fatalf("archive changed: %d bytes, was %d", len(after), len(before))
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package compressing
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsafePath is returned for an archive entry whose path could
+// place it outside the destination directory.
+type ErrUnsafePath struct {
+	Path   string
+	Reason string
+}
+
+func (e *ErrUnsafePath) Error() string {
+	return fmt.Sprintf("unsafe path %q in archive: %s", e.Path, e.Reason)
+}
+
+// checkPaths проверяет пути записей до распаковки: пути должны быть
+// относительными, без "..", и не проходить через символические ссылки архива
+func checkPaths(entries []File) error {
+	symlinks := make(map[string]bool)
+	for _, e := range entries {
+		if e.Kind == KindSymlink {
+			symlinks[filepath.Clean(e.Path)] = true
+		}
+	}
+
+	for _, e := range entries {
+		if err := checkPath(e.Path); err != nil {
+			return err
+		}
+		for dir := filepath.Dir(filepath.Clean(e.Path)); dir != "."; dir = filepath.Dir(dir) {
+			if symlinks[dir] {
+				return &ErrUnsafePath{e.Path, "parent directory is a symlink of the archive"}
+			}
+		}
+	}
+	return nil
+}
+
+func checkPath(path string) error {
+	switch {
+	case path == "":
+		return &ErrUnsafePath{path, "empty path"}
+	case filepath.IsAbs(path), filepath.VolumeName(path) != "", isSeparator(rune(path[0])):
+		return &ErrUnsafePath{path, "absolute path"}
+	case filepath.Clean(path) == ".":
+		return &ErrUnsafePath{path, "path of the destination itself"}
+	}
+	for _, part := range strings.FieldsFunc(path, isSeparator) {
+		if part == ".." {
+			return &ErrUnsafePath{path, "reference to a parent directory"}
+		}
+	}
+	return nil
+}
+
+// isSeparator учитывает и '/', и разделитель ОС: в архиве, созданном
+// на другой ОС, может встретиться любой из них
+func isSeparator(r rune) bool { return r == '/' || r < 0x80 && os.IsPathSeparator(uint8(r)) }
+
+// entryPath возвращает путь записи внутри dstpath. Если какая-то часть
+// пути уже существует и является символической ссылкой, запись через
+// нее попала бы за пределы dstpath.
+func entryPath(dstpath, path string, opts DecompressOptions) (string, error) {
+	if opts.AllowUnsafePaths {
+		return filepath.Join(dstpath, path), nil
+	}
+
+	current := dstpath
+	for _, part := range strings.Split(filepath.Clean(path), string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return "", &ErrUnsafePath{path, fmt.Sprintf("%s is a symlink", current)}
+		}
+	}
+	return filepath.Join(dstpath, path), nil
+}
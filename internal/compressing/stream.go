@@ -0,0 +1,330 @@
+package compressing
+
+import (
+	"bufio"
+	"cmp"
+	"compressor/internal/utiles"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Записи потокового архива (FlagStream) между заголовком и футером:
+// байт вида и секция с данными. За записью обычного файла идут его данные.
+const (
+	recordCodec byte = 'C'
+	recordEntry byte = 'F'
+	recordEnd   byte = 'E'
+)
+
+// ErrNotStream is returned by DecompressStream for archives written
+// without FlagStream, they can only be read from a file.
+var ErrNotStream = errors.New("archive is not a stream archive")
+
+// CompressStream compresses the files as CompressFilesWith does and writes
+// the archive to dst strictly sequentially, so dst may be a pipe. The
+// payloads are staged in spool first: codec bodies have to be written
+// before them and are known only after compression.
+//
+// contentSize is the size of the header, the records and the payloads.
+func CompressStream(
+	comps []CompressionBase, fallback FastCompressor,
+	pathes []string, spool *os.File, dst io.Writer, prog *utiles.Progress[int64],
+) (contentSize int64, footerSize int64, err error) {
+	if len(comps) != len(pathes) {
+		return 0, 0, fmt.Errorf("got %d compressors for %d files", len(comps), len(pathes))
+	}
+	fileMap, err := newEntries(pathes)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	codecs, bodies, _, err := compressEntries(comps, fallback, pathes, fileMap, spool, 0, 0, prog)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	buf := bufio.NewWriter(dst)
+	w := &utiles.CountingWriter{W: buf}
+	if err := writeHeader(newHeader(FlagStream), w); err != nil {
+		return 0, 0, &ErrCompression{err}
+	}
+	for i, codec := range codecs {
+		body, err := marshalBody(bodies[i])
+		if err != nil {
+			return 0, 0, &ErrCompression{err}
+		}
+		e := NewEncoder()
+		e.String(codec.Type)
+		e.Uvarint(uint64(codec.Version))
+		e.Blob(body)
+		if err := writeRecord(w, recordCodec, e.Bytes()); err != nil {
+			return 0, 0, &ErrCompression{err}
+		}
+	}
+
+	for _, i := range streamOrder(fileMap) {
+		f := &fileMap[i]
+		// смещение в локальной записи не нужно: данные идут сразу за ней
+		local := *f
+		local.Offset = 0
+		if err := writeRecord(w, recordEntry, local.marshal()); err != nil {
+			return 0, 0, &ErrCompression{err}
+		}
+		if f.Kind != KindFile {
+			continue
+		}
+		payload := io.NewSectionReader(spool, f.Offset, f.Size)
+		f.Offset = w.N
+		if n, err := io.Copy(w, payload); err != nil || n != f.Size {
+			return 0, 0, &ErrCompression{fmt.Errorf("%s: payload copy failed: %v", f.Path, err)}
+		}
+	}
+	if err := writeRecord(w, recordEnd, nil); err != nil {
+		return 0, 0, &ErrCompression{err}
+	}
+
+	contentSize = w.N
+	if footerSize, err = finishArchive(newFooter(codecs, fileMap, bodies), w, contentSize); err != nil {
+		return 0, 0, err
+	}
+	if err := buf.Flush(); err != nil {
+		return 0, 0, &ErrCompression{err}
+	}
+	return contentSize, footerSize, nil
+}
+
+// streamOrder возвращает порядок записей в потоке: каталоги, затем файлы
+// в порядке их данных, затем ссылки, которые создаются после файлов
+func streamOrder(fileMap []File) []int {
+	rank := func(f *File) int {
+		switch f.Kind {
+		case KindDir:
+			return 0
+		case KindFile:
+			return 1
+		}
+		return 2
+	}
+	order := make([]int, len(fileMap))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		fa, fb := &fileMap[a], &fileMap[b]
+		if c := cmp.Compare(rank(fa), rank(fb)); c != 0 || fa.Kind != KindFile {
+			return c
+		}
+		return cmp.Compare(fa.Offset, fb.Offset)
+	})
+	return order
+}
+
+func writeRecord(w io.Writer, kind byte, data []byte) error {
+	if _, err := w.Write([]byte{kind}); err != nil {
+		return err
+	}
+	_, err := writeSection(data, w)
+	return err
+}
+
+// DecompressStream extracts a stream archive read sequentially from src,
+// which may be a pipe, with the same checks and options as Decompress.
+// Files are decoded as their records arrive. A hard link can't be
+// extracted without the file it points to. ErrNotStream is returned for
+// other archives.
+func DecompressStream(
+	factory DecompressorFactory, src io.Reader, dstpath string, opts DecompressOptions,
+	prog *utiles.Progress[int64],
+) ([]*DecompressedFile, error) {
+	r := bufio.NewReader(src)
+	header, err := readHeader(r)
+	if err != nil {
+		return nil, &ErrDecompression{err}
+	}
+	if header.Flags&FlagStream == 0 {
+		return nil, &ErrDecompression{ErrNotStream}
+	}
+
+	var (
+		decomps  []Decompressor
+		bodies   []Body
+		entries  []File
+		regular  []int
+		files    []*os.File
+		output   []*DecompressedFile
+		mismatch []string
+		dirs     createdDirs
+		matched  = make([]bool, len(opts.Include))
+	)
+	defer func() { utiles.CloseFiles(files) }()
+	fail := func(err error) ([]*DecompressedFile, error) {
+		removeFiles(files)
+		dirs.remove()
+		return nil, &ErrDecompression{err}
+	}
+
+records:
+	for {
+		kind, err := r.ReadByte()
+		if err == io.EOF {
+			return fail(io.ErrUnexpectedEOF)
+		} else if err != nil {
+			return fail(err)
+		}
+		data, _, err := readSection(r)
+		if err != nil {
+			return fail(err)
+		}
+
+		switch kind {
+		case recordCodec:
+			decomp, body, err := streamCodec(factory, data)
+			if err != nil {
+				return fail(err)
+			}
+			decomps, bodies = append(decomps, decomp), append(bodies, body)
+		case recordEntry:
+			var f File
+			if err := f.unmarshal(data); err != nil {
+				return fail(err)
+			}
+			if _, err := regularFiles([]File{f}, len(decomps)); err != nil {
+				return fail(err)
+			}
+			if f.Kind == KindFile && f.Size < 0 {
+				return fail(fmt.Errorf("invalid size %d of %s", f.Size, f.Path))
+			}
+			selected, err := isSelected(f.Path, opts.Include, opts.Exclude, matched)
+			if err != nil {
+				return fail(err)
+			}
+			if !selected {
+				if f.Kind == KindFile {
+					if _, err := io.CopyN(io.Discard, r, f.Size); err != nil {
+						return fail(err)
+					}
+				}
+				continue
+			}
+			if !opts.AllowUnsafePaths {
+				if err := checkPath(f.Path); err != nil {
+					return fail(err)
+				}
+			}
+			entries = append(entries, f)
+
+			switch f.Kind {
+			case KindDir:
+				if err := createDirs([]File{f}, dstpath, opts, &dirs); err != nil {
+					return fail(err)
+				}
+			case KindFile:
+				out, file, err := streamFile(decomps, bodies, r, f, dstpath, opts, &dirs, prog)
+				if file != nil {
+					files = append(files, file)
+				}
+				if err != nil {
+					return fail(err)
+				}
+				regular = append(regular, len(entries)-1)
+				output = append(output, out)
+				if out.NewChecksum != out.OldChecksum {
+					mismatch = append(mismatch, f.Path)
+				}
+			}
+		case recordEnd:
+			break records
+		default:
+			return fail(fmt.Errorf("unknown record %q", kind))
+		}
+	}
+
+	for j, p := range opts.Include {
+		if !matched[j] {
+			return fail(&ErrNoMatch{p})
+		}
+	}
+	if !opts.AllowUnsafePaths {
+		if err := checkPaths(entries); err != nil {
+			return fail(err)
+		}
+	}
+	extracted := make(map[string]bool, len(regular))
+	for _, i := range regular {
+		extracted[filepath.Clean(entries[i].Path)] = true
+	}
+	for _, e := range entries {
+		if e.Kind == KindHardlink && !extracted[filepath.Clean(e.LinkTarget)] {
+			return fail(fmt.Errorf("hard link %s points to %s, which is not extracted", e.Path, e.LinkTarget))
+		}
+	}
+
+	// футер не нужен, но дочитывается, чтобы не оборвать канал пишущей стороне
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return fail(err)
+	}
+	return finishExtract(entries, regular, files, dirs, output, mismatch, dstpath, opts)
+}
+
+// streamCodec создает декомпрессор по записи кодека
+func streamCodec(factory DecompressorFactory, data []byte) (Decompressor, Body, error) {
+	d := NewDecoder(data)
+	codec := Codec{d.String(), d.Int()}
+	bodyData := d.Blob()
+	if err := d.Err(); err != nil {
+		return nil, nil, fmt.Errorf("codec record: %w", err)
+	}
+	decomp := factory(codec.Type, codec.Version)
+	if decomp == nil {
+		return nil, nil, fmt.Errorf("unsupported compression type: %s", codec.Type)
+	}
+	body := decomp.FooterBodyType()
+	if err := unmarshalBody(bodyData, body); err != nil {
+		return nil, nil, err
+	}
+	if err := decomp.Preprocessing(body, nil); err != nil {
+		return nil, nil, err
+	}
+	return decomp, body, nil
+}
+
+// streamFile декодирует данные файла, идущие в r за его записью. Созданный
+// файл возвращается и при ошибке, чтобы его можно было удалить.
+func streamFile(
+	decomps []Decompressor, bodies []Body, r io.Reader, f File, dstpath string, opts DecompressOptions,
+	dirs *createdDirs, prog *utiles.Progress[int64],
+) (*DecompressedFile, *os.File, error) {
+	path, err := entryPath(dstpath, f.Path, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := dirs.mkdirAll(filepath.Dir(path)); err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hasher := sha256.New()
+	payload := &io.LimitedReader{R: r, N: f.Size}
+	input := &DecompressionInput{bodies[f.Codec], payload, io.MultiWriter(file, hasher), f}
+	if err := decomps[f.Codec].DecompressFile(input, prog); err != nil {
+		return nil, file, err
+	}
+	// декодер может не дочитать данные до конца
+	if _, err := io.Copy(io.Discard, payload); err != nil {
+		return nil, file, err
+	}
+	if payload.N > 0 {
+		return nil, file, io.ErrUnexpectedEOF
+	}
+	out := &DecompressedFile{Path: file.Name(), OldChecksum: f.Checksum, NewChecksum: hex.EncodeToString(hasher.Sum(nil))}
+	return out, file, nil
+}
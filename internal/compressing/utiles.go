@@ -1,69 +1,143 @@
-package compressing
-
-import (
-	"crypto/sha256"
-	"encoding/hex"
-	"fmt"
-	"io"
-	"os"
-	"path/filepath"
-	"strings"
-)
-
-// checksum computes the SHA-256 checksum of the data read from r and returns it as a hex string.
-func checksum(r io.Reader) (string, error) {
-	hasher := sha256.New()
-	if _, err := io.Copy(hasher, r); err != nil {
-		return "", err
-	}
-	return hex.EncodeToString(hasher.Sum(nil)), nil
-}
-
-func formatPathes(fileMap []File) error {
-	if len(fileMap) == 1 {
-		fileMap[0].Path = filepath.Base(fileMap[0].Path)
-		return nil
-	}
-	splittedPathes := make([][]string, len(fileMap))
-	for i, f := range fileMap {
-		cleaned := filepath.Clean(f.Path)
-		splittedPathes[i] = strings.Split(cleaned, string(filepath.Separator))
-	}
-
-	shortest := splittedPathes[0]
-	for _, p := range splittedPathes {
-		if len(p) < len(shortest) {
-			shortest = p
-		}
-	}
-
-	prefixLen := len(shortest)
-	for _, p := range splittedPathes {
-		for i := 0; i < prefixLen; i++ {
-			if p[i] != shortest[i] {
-				prefixLen = i
-				break
-			}
-		}
-		if prefixLen == 0 {
-			break
-		}
-	}
-
-	if prefixLen == 0 {
-		return fmt.Errorf("no common prefix found")
-	}
-
-	for i, p := range splittedPathes {
-		suffix := p[prefixLen:]
-		fileMap[i].Path = filepath.Join(suffix...)
-	}
-	return nil
-}
-
-func removeFiles(files []*os.File) {
-	for _, f := range files {
-		f.Close()
-		os.Remove(f.Name())
-	}
-}
+package compressing
+
+import (
+	"compressor/internal/utiles"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksum computes the SHA-256 checksum of the data read from r and returns it as a hex string.
+func checksum(r io.Reader) (string, error) {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// scanEntries создает записи архива по путям, не переходя по символическим
+// ссылкам. Повторные жесткие ссылки на файл становятся записями KindHardlink,
+// linkTo хранит номер записи с содержимым файла.
+func scanEntries(pathes []string) (entries []File, linkTo map[int]int, err error) {
+	type fileID struct{ dev, ino uint64 }
+	seen := make(map[fileID]int)
+	entries = make([]File, len(pathes))
+	linkTo = make(map[int]int)
+	for i, path := range pathes {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries[i].Path = path
+		entries[i].setAttributes(info)
+
+		switch mode := info.Mode(); {
+		case mode.IsRegular():
+			dev, ino, links, ok := utiles.FileID(info)
+			if !ok || links < 2 {
+				break
+			}
+			if first, ok := seen[fileID{dev, ino}]; ok {
+				entries[i].Kind = KindHardlink
+				linkTo[i] = first
+			} else {
+				seen[fileID{dev, ino}] = i
+			}
+		case mode.IsDir():
+			entries[i].Kind = KindDir
+		case mode&fs.ModeSymlink != 0:
+			entries[i].Kind = KindSymlink
+			if entries[i].LinkTarget, err = os.Readlink(path); err != nil {
+				return nil, nil, err
+			}
+		default:
+			return nil, nil, fmt.Errorf("unsupported file type %v: %s", mode.Type(), path)
+		}
+	}
+	return entries, linkTo, nil
+}
+
+func formatPathes(fileMap []File) error {
+	if len(fileMap) == 1 {
+		fileMap[0].Path = filepath.Base(fileMap[0].Path)
+		return nil
+	}
+	splittedPathes := make([][]string, len(fileMap))
+	for i, f := range fileMap {
+		cleaned := filepath.Clean(f.Path)
+		splittedPathes[i] = strings.Split(cleaned, string(filepath.Separator))
+	}
+
+	shortest := splittedPathes[0]
+	for _, p := range splittedPathes {
+		if len(p) < len(shortest) {
+			shortest = p
+		}
+	}
+
+	// путь каталога может быть префиксом путей его содержимого,
+	// но от каждого пути должно остаться хотя бы имя
+	prefixLen := len(shortest) - 1
+	for _, p := range splittedPathes {
+		for i := 0; i < prefixLen; i++ {
+			if p[i] != shortest[i] {
+				prefixLen = i
+				break
+			}
+		}
+		if prefixLen == 0 {
+			break
+		}
+	}
+
+	if prefixLen == 0 {
+		return fmt.Errorf("no common prefix found")
+	}
+
+	for i, p := range splittedPathes {
+		suffix := p[prefixLen:]
+		fileMap[i].Path = filepath.Join(suffix...)
+	}
+	return nil
+}
+
+func removeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+		os.Remove(f.Name())
+	}
+}
+
+// createdDirs — каталоги, созданные при распаковке, в порядке создания
+type createdDirs []string
+
+// mkdirAll создает каталог path вместе с родителями и запоминает те из
+// них, которых не было
+func (c *createdDirs) mkdirAll(path string) error {
+	var missing []string
+	for p := path; ; p = filepath.Dir(p) {
+		if _, err := os.Lstat(p); err == nil || filepath.Dir(p) == p {
+			break
+		}
+		missing = append(missing, p)
+	}
+	// при ошибке часть каталогов может быть уже создана
+	for i := len(missing) - 1; i >= 0; i-- {
+		*c = append(*c, missing[i])
+	}
+	return os.MkdirAll(path, 0755)
+}
+
+// remove удаляет созданные каталоги, начиная с вложенных. Непустые
+// каталоги остаются
+func (c createdDirs) remove() {
+	for i := len(c) - 1; i >= 0; i-- {
+		os.Remove(c[i])
+	}
+}
@@ -0,0 +1,66 @@
+package deflate
+
+import (
+	"compress/flate"
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+)
+
+const (
+	CompressionType = "DEFLATE"
+
+	MinLevel     = flate.BestSpeed
+	MaxLevel     = flate.BestCompression
+	DefaultLevel = 6
+)
+
+// Params is the footer body. Streams are decoded without it,
+// the level is stored for information only.
+type Params struct {
+	Level int
+}
+
+func (p *Params) EncodeBody(e *comp.Encoder) { e.Uvarint(uint64(p.Level)) }
+
+func (p *Params) DecodeBody(d *comp.Decoder) error {
+	p.Level = d.Int()
+	return d.Err()
+}
+
+// Compressor сжимает каждый файл в отдельный поток raw DEFLATE (RFC 1951),
+// поэтому любой файл архива можно передать внешнему декодеру DEFLATE.
+type Compressor struct {
+	Level int
+}
+
+func NewCompressor(level int) (*Compressor, error) {
+	if level < MinLevel || level > MaxLevel {
+		return nil, fmt.Errorf("compression level must be in range [%d, %d]: %d", MinLevel, MaxLevel, level)
+	}
+	return &Compressor{level}, nil
+}
+
+func (c *Compressor) Preprocessing(_ []io.Reader) error { return nil }
+
+func (c *Compressor) CompressorData() (string, comp.Body) {
+	return CompressionType, &Params{c.Level}
+}
+
+func (c *Compressor) CompressFile(
+	src io.Reader, dst io.Writer, prog *utiles.Progress[int64],
+) (size int64, trailingBits uint8, err error) {
+	counter := &utiles.CountingWriter{W: dst}
+	fw, err := flate.NewWriter(counter, c.Level)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := io.Copy(fw, &utiles.ProgressReader{R: src, Prog: prog}); err != nil {
+		return 0, 0, err
+	}
+	if err := fw.Close(); err != nil {
+		return 0, 0, err
+	}
+	return counter.N, 0, nil
+}
@@ -0,0 +1,68 @@
+package deflate
+
+import (
+	"bytes"
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]byte, 50_000)
+	rnd.Read(random)
+	inputs := map[string][]byte{
+		"empty":  nil,
+		"text":   bytes.Repeat([]byte("every file is a raw DEFLATE stream\n"), 2000),
+		"random": random,
+	}
+	for _, level := range []int{MinLevel, MinLevel + 1, DefaultLevel, MaxLevel - 1, MaxLevel} {
+		for name, data := range inputs {
+			t.Run(fmt.Sprintf("level %d %s", level, name), func(t *testing.T) {
+				c, err := NewCompressor(level)
+				if err != nil {
+					t.Fatal(err)
+				}
+				prog := utiles.NewProgress[int64](0)
+				prog.Close()
+
+				var payload bytes.Buffer
+				size, trailingBits, err := c.CompressFile(bytes.NewReader(data), &payload, prog)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if size != int64(payload.Len()) || trailingBits != 0 {
+					t.Fatalf("got size %d and %d trailing bits, wrote %d bytes", size, trailingBits, payload.Len())
+				}
+				_, body := c.CompressorData()
+				if p := body.(*Params); p.Level != level {
+					t.Fatalf("got level %d in the body, want %d", p.Level, level)
+				}
+
+				d := NewDecompressor()
+				if err := d.Preprocessing(body, nil); err != nil {
+					t.Fatal(err)
+				}
+				var out bytes.Buffer
+				entry := comp.File{Size: size}
+				input := &comp.DecompressionInput{Body: body, SourceFile: bytes.NewReader(payload.Bytes()), DestFile: &out, Entry: entry}
+				if err := d.DecompressFile(input, prog); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(out.Bytes(), data) {
+					t.Fatalf("got %d bytes, want %d: data differs", out.Len(), len(data))
+				}
+			})
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	for _, level := range []int{-2, -1, 0, MaxLevel + 1, 100} {
+		if _, err := NewCompressor(level); err == nil {
+			t.Errorf("level %d was accepted", level)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package deflate
+
+import (
+	"compress/flate"
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+)
+
+type Decompressor struct{}
+
+func NewDecompressor() *Decompressor { return &Decompressor{} }
+
+func (d *Decompressor) FooterBodyType() comp.Body { return &Params{} }
+
+func (d *Decompressor) Preprocessing(data comp.Body, _ io.ReadSeeker) error {
+	if _, ok := data.(*Params); !ok {
+		return fmt.Errorf("unexpected footer body %T", data)
+	}
+	return nil
+}
+
+func (d *Decompressor) DecompressFile(dd *comp.DecompressionInput, prog *utiles.Progress[int64]) error {
+	fr := flate.NewReader(&utiles.ProgressReader{R: dd.SourceFile, Prog: prog})
+	defer fr.Close()
+	_, err := io.Copy(dd.DestFile, fr)
+	return err
+}
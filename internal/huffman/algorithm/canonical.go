@@ -0,0 +1,62 @@
+package algorithm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CanonicalOrder возвращает символы, упорядоченные по длине кода, а затем по значению
+func CanonicalOrder(lengths map[string]uint8) []string {
+	symbols := make([]string, 0, len(lengths))
+	for symb := range lengths {
+		symbols = append(symbols, symb)
+	}
+	slices.SortFunc(symbols, func(a, b string) int {
+		if lengths[a] != lengths[b] {
+			return int(lengths[a]) - int(lengths[b])
+		}
+		return strings.Compare(a, b)
+	})
+	return symbols
+}
+
+// CanonicalCodes строит канонические коды Хаффмана по длинам кодов.
+// Коды однозначно восстанавливаются из длин, поэтому хранить сами коды не нужно.
+func CanonicalCodes(lengths map[string]uint8) map[string]Code {
+	codes := make(map[string]Code, len(lengths))
+	var (
+		next    uint64
+		prevLen uint8
+	)
+	for _, symb := range CanonicalOrder(lengths) {
+		length := lengths[symb]
+		next <<= length - prevLen
+		codes[symb] = Code{next, length}
+		next++
+		prevLen = length
+	}
+	return codes
+}
+
+// ValidateLengths проверяет, что длины кодов задают префиксный код (неравенство Крафта)
+func ValidateLengths(lengths map[string]uint8) error {
+	counts := make([]uint64, MaxCodeLen+1)
+	for symb, length := range lengths {
+		if length == 0 || length > MaxCodeLen {
+			return fmt.Errorf("invalid code length %d for symbol %q", length, symb)
+		}
+		counts[length]++
+	}
+	// free — число свободных кодов текущей длины; его ограничиваем
+	// количеством символов, чтобы избежать переполнения
+	free, total := uint64(1), uint64(len(lengths))
+	for length := 1; length <= MaxCodeLen; length++ {
+		free = min(free*2, total)
+		if counts[length] > free {
+			return fmt.Errorf("code lengths don't form a prefix code")
+		}
+		free -= counts[length]
+	}
+	return nil
+}
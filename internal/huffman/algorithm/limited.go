@@ -0,0 +1,91 @@
+package algorithm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// packageMerge строит оптимальные длины кодов не длиннее maxLen.
+//
+// Список уровня 0 состоит из символов, отсортированных по частоте; список
+// уровня j получается слиянием символов с парами ("пакетами") соседних
+// элементов уровня j-1. Из верхнего списка берутся первые 2n-2 элемента, и длина
+// кода символа равна числу уровней, на которых он попал в выбранные элементы.
+// Сами пакеты не хранятся: для каждого уровня достаточно знать, какие
+// элементы списка были символами, а какие пакетами.
+func packageMerge(frequencies map[string]uint64, maxLen uint8) (map[string]uint8, error) {
+	n := len(frequencies)
+	if n > 1 && maxLen < 64 && n > 1<<maxLen {
+		return nil, fmt.Errorf("max code length %d is too small for %d symbols", maxLen, n)
+	}
+
+	symbols := make([]string, 0, n)
+	for symb := range frequencies {
+		symbols = append(symbols, symb)
+	}
+	slices.SortFunc(symbols, func(a, b string) int {
+		if frequencies[a] != frequencies[b] {
+			if frequencies[a] < frequencies[b] {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+
+	lengths := make(map[string]uint8, n)
+	if n <= 2 {
+		for _, symb := range symbols {
+			lengths[symb] = 1
+		}
+		return lengths, nil
+	}
+
+	weights := make([]uint64, n)
+	for i, symb := range symbols {
+		weights[i] = frequencies[symb]
+	}
+
+	// isLeaf[j][i] — является ли i-й элемент списка уровня j символом
+	isLeaf := make([][]bool, maxLen)
+	isLeaf[0] = slices.Repeat([]bool{true}, n)
+	list := weights
+	for j := 1; j < int(maxLen); j++ {
+		packages := make([]uint64, len(list)/2)
+		for i := range packages {
+			packages[i] = list[2*i] + list[2*i+1]
+		}
+
+		merged := make([]uint64, 0, n+len(packages))
+		kinds := make([]bool, 0, n+len(packages))
+		l, p := 0, 0
+		for l < n || p < len(packages) {
+			if p == len(packages) || l < n && weights[l] <= packages[p] {
+				merged, kinds = append(merged, weights[l]), append(kinds, true)
+				l++
+			} else {
+				merged, kinds = append(merged, packages[p]), append(kinds, false)
+				p++
+			}
+		}
+		list, isLeaf[j] = merged, kinds
+	}
+
+	count := 2*n - 2
+	for j := int(maxLen) - 1; j >= 0; j-- {
+		leaves, packages := 0, 0
+		for _, leaf := range isLeaf[j][:count] {
+			if leaf {
+				leaves++
+			} else {
+				packages++
+			}
+		}
+		for _, symb := range symbols[:leaves] {
+			lengths[symb]++
+		}
+		count = 2 * packages
+	}
+	return lengths, nil
+}
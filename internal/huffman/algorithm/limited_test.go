@@ -0,0 +1,122 @@
+package algorithm
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// fibonacci возвращает n символов с частотами-числами Фибоначчи: дерево
+// Хаффмана для них — цепочка глубины n-1
+func fibonacci(n int) map[string]uint64 {
+	freqs := make(map[string]uint64, n)
+	a, b := uint64(1), uint64(1)
+	for i := 0; i < n; i++ {
+		freqs[fmt.Sprintf("s%02d", i)] = a
+		a, b = b, a+b
+	}
+	return freqs
+}
+
+// kraftSum возвращает сумму 2^(max-l) по длинам l и 2^max
+func kraftSum(lengths map[string]uint8) (sum, full uint64) {
+	var maxLen uint8
+	for _, l := range lengths {
+		maxLen = max(maxLen, l)
+	}
+	for _, l := range lengths {
+		sum += 1 << (maxLen - l)
+	}
+	return sum, 1 << maxLen
+}
+
+func cost(freqs map[string]uint64, lengths map[string]uint8) uint64 {
+	var bits uint64
+	for symb, freq := range freqs {
+		bits += freq * uint64(lengths[symb])
+	}
+	return bits
+}
+
+func TestLimitedCodeLengths(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := make(map[string]uint64)
+	for i := 0; i < 300; i++ {
+		random[fmt.Sprintf("r%03d", i)] = uint64(rnd.Intn(1000) + 1)
+	}
+	cases := []struct {
+		name    string
+		freqs   map[string]uint64
+		maxLen  uint8
+		wantMax uint8 // 0 — не проверять
+		wantErr bool
+	}{
+		{"one symbol", map[string]uint64{"a": 5}, 1, 1, false},
+		{"two symbols", map[string]uint64{"a": 1, "b": 100}, 1, 1, false},
+		{"fibonacci unlimited", fibonacci(12), 11, 11, false},
+		{"fibonacci limited", fibonacci(12), 8, 8, false},
+		{"fibonacci tight", fibonacci(16), 4, 4, false},
+		{"exactly 2^maxLen", fibonacci(8), 3, 3, false},
+		{"too small", fibonacci(9), 3, 0, true},
+		{"random", random, 10, 10, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			huff := NewHuffmanTree(1)
+			if err := huff.BuildTree(c.freqs); err != nil {
+				t.Fatal(err)
+			}
+			lengths, err := huff.LimitedCodeLengths(c.maxLen)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", lengths)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lengths) != len(c.freqs) {
+				t.Fatalf("got %d lengths for %d symbols", len(lengths), len(c.freqs))
+			}
+			if err := ValidateLengths(lengths); err != nil {
+				t.Fatal(err)
+			}
+
+			var longest uint8
+			for _, l := range lengths {
+				longest = max(longest, l)
+			}
+			if c.wantMax != 0 && longest != c.wantMax {
+				t.Fatalf("longest code is %d bits, want %d", longest, c.wantMax)
+			}
+			// у одного символа код в 1 бит, дерево не полное
+			if sum, full := kraftSum(lengths); len(lengths) > 1 && sum != full {
+				t.Fatalf("Kraft sum is %d/%d, want 1", sum, full)
+			}
+		})
+	}
+}
+
+// Если ограничение не мешает, package-merge дает коды той же стоимости,
+// что и дерево Хаффмана
+func TestPackageMergeOptimal(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	for n := 3; n <= 40; n++ {
+		freqs := make(map[string]uint64, n)
+		for i := 0; i < n; i++ {
+			freqs[fmt.Sprintf("s%02d", i)] = uint64(rnd.Intn(50) + 1)
+		}
+		huff := NewHuffmanTree(1)
+		if err := huff.BuildTree(freqs); err != nil {
+			t.Fatal(err)
+		}
+		merged, err := packageMerge(freqs, MaxCodeLen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := cost(freqs, merged), cost(freqs, huff.CodeLengths()); got != want {
+			t.Fatalf("%d symbols: package-merge costs %d bits, Huffman %d", n, got, want)
+		}
+	}
+}
@@ -1,158 +1,126 @@
-package algorithm
-
-import (
-	"compressor/internal/utiles"
-	"container/heap"
-	"io"
-	"strings"
-	"sync"
-)
-
-type code struct {
-	bits []byte
-	sync.RWMutex
-}
-
-// NewCode создает новый код длинны length бит
-func newCode(length uint64) *code {
-	return &code{
-		bits: make([]byte, length),
-	}
-}
-
-func (c *code) writeBit(bit bool) {
-	if bit {
-		c.bits = append(c.bits, 1)
-	} else {
-		c.bits = append(c.bits, 0)
-	}
-}
-
-func (c *code) bytes() []byte {
-	bitCount := len(c.bits)
-	if bitCount == 0 {
-		return nil
-	}
-
-	byteCount := (bitCount + 7) / 8
-	result := make([]byte, byteCount)
-
-	for i, bit := range c.bits {
-		if bit != 0 {
-			ind := i / 8
-			offset := 7 - (i % 8) // старший бит в байте — первый
-			result[ind] |= 1 << offset
-		}
-	}
-
-	return result
-}
-
-func (c *code) String() string {
-	var sb strings.Builder
-	for _, b := range c.bits {
-		sb.WriteByte('0' + b)
-	}
-	return sb.String()
-}
-
-type HuffmanTree struct {
-	root      *node
-	size      int
-	BlockSize int
-	Alphabet  [][]byte
-}
-
-func NewHuffmanTree(blockSize int) *HuffmanTree {
-	return &HuffmanTree{
-		root:      nil,
-		size:      0,
-		BlockSize: blockSize,
-		Alphabet:  nil,
-	}
-}
-
-// CountFrequencies читает файл блоками и возвращает частоты блоков
-func CountFrequencies(file io.Reader, blockSize int) (map[string]uint64, error) {
-	buf := make([]byte, blockSize)
-	frequencies := make(map[string]uint64)
-
-	for {
-		n, err := file.Read(buf)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			if err != io.ErrUnexpectedEOF {
-				return nil, err
-			}
-		}
-		block := string(buf[:n])
-		frequencies[block]++
-	}
-
-	return frequencies, nil
-}
-
-func (huff *HuffmanTree) BuildTree(frequencies map[string]uint64) error {
-	if len(frequencies) == 0 {
-		return nil
-	}
-	nodes := make(nodesHeap, 0, len(frequencies))
-	alphabet := make([][]byte, 0, len(frequencies))
-	for symb, freq := range frequencies {
-		bytes := []byte(symb)
-		heap.Push(&nodes, newNode(bytes, freq))
-		alphabet = append(alphabet, bytes)
-	}
-	huff.Alphabet = alphabet
-
-	for len(nodes) != 1 {
-		node1 := heap.Pop(&nodes).(*node)
-		node2 := heap.Pop(&nodes).(*node)
-		heap.Push(&nodes, combine(node1, node2))
-	}
-	huff.root = heap.Pop(&nodes).(*node)
-	return nil
-}
-
-func (huff *HuffmanTree) EncodeTable() map[string][]byte {
-	codes := utiles.NewSafeMap[string, *code]()
-	var wg sync.WaitGroup
-	wg.Add(len(huff.Alphabet))
-
-	for i := range huff.Alphabet {
-		block := huff.Alphabet[i]
-		go func(block []byte) {
-			defer wg.Done()
-			c := newCode(0)
-			encodingSearch(huff.root, block, c, true, 0)
-			codes.Store(string(block), c)
-		}(block)
-	}
-	wg.Wait()
-
-	bytesFromCodes := make(map[string][]byte)
-	codes.Range(func(s string, c *code) (pass bool) {
-		bytesFromCodes[s] = c.bytes()
-		return true
-	})
-
-	return bytesFromCodes
-}
-
-func encodingSearch(n *node, value []byte, c *code, bitFlag bool, height uint64) {
-	if n == nil || !n.hasValue(value) {
-		return
-	}
-
-	if height > 0 {
-		if bitFlag {
-			c.writeBit(true)
-		} else {
-			c.writeBit(false)
-		}
-	}
-	encodingSearch(n.right, value, c, true, height+1)
-	encodingSearch(n.left, value, c, false, height+1)
-}
+package algorithm
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+)
+
+// MaxCodeLen is the longest code which fits into Code.
+const MaxCodeLen = 64
+
+// Code is a Huffman code of Len bits stored in the lowest bits of Bits.
+type Code struct {
+	Bits uint64
+	Len  uint8
+}
+
+type HuffmanTree struct {
+	root        *node
+	size        int
+	frequencies map[string]uint64
+	BlockSize   int
+	Alphabet    [][]byte
+}
+
+func NewHuffmanTree(blockSize int) *HuffmanTree {
+	return &HuffmanTree{
+		root:      nil,
+		size:      0,
+		BlockSize: blockSize,
+		Alphabet:  nil,
+	}
+}
+
+// CountFrequencies читает файл блоками и возвращает частоты блоков
+func CountFrequencies(file io.Reader, blockSize int) (map[string]uint64, error) {
+	buf := make([]byte, blockSize)
+	frequencies := make(map[string]uint64)
+
+	for {
+		// последний блок файла может быть короче blockSize
+		n, err := io.ReadFull(file, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		block := string(buf[:n])
+		frequencies[block]++
+	}
+
+	return frequencies, nil
+}
+
+func (huff *HuffmanTree) BuildTree(frequencies map[string]uint64) error {
+	if len(frequencies) == 0 {
+		return nil
+	}
+	nodes := make(nodesHeap, 0, len(frequencies))
+	alphabet := make([][]byte, 0, len(frequencies))
+	for symb, freq := range frequencies {
+		bytes := []byte(symb)
+		heap.Push(&nodes, newNode(bytes, freq))
+		alphabet = append(alphabet, bytes)
+	}
+	huff.Alphabet = alphabet
+
+	for len(nodes) != 1 {
+		node1 := heap.Pop(&nodes).(*node)
+		node2 := heap.Pop(&nodes).(*node)
+		heap.Push(&nodes, combine(node1, node2))
+	}
+	huff.root = heap.Pop(&nodes).(*node)
+	huff.frequencies = frequencies
+	return nil
+}
+
+// CodeLengths возвращает длины кодов символов алфавита (глубины листьев дерева),
+// ограниченные MaxCodeLen
+func (huff *HuffmanTree) CodeLengths() map[string]uint8 {
+	// в алфавите не может быть больше 2^MaxCodeLen символов, поэтому ошибки нет
+	lengths, _ := huff.LimitedCodeLengths(MaxCodeLen)
+	return lengths
+}
+
+// LimitedCodeLengths возвращает длины кодов не длиннее maxLen. Если дерево
+// глубже, длины строятся алгоритмом package-merge по частотам символов.
+func (huff *HuffmanTree) LimitedCodeLengths(maxLen uint8) (map[string]uint8, error) {
+	if maxLen == 0 || maxLen > MaxCodeLen {
+		return nil, fmt.Errorf("max code length must be in range [1, %d]", MaxCodeLen)
+	}
+	if huff.root != nil && huff.root.height > uint64(maxLen) {
+		return packageMerge(huff.frequencies, maxLen)
+	}
+
+	lengths := make(map[string]uint8, len(huff.Alphabet))
+	if huff.root == nil {
+		return lengths, nil
+	}
+	// единственный символ всё равно должен занимать хотя бы один бит
+	if huff.root.left == nil && huff.root.right == nil {
+		for symb := range huff.root.values {
+			lengths[symb] = 1
+		}
+		return lengths, nil
+	}
+	leafDepths(huff.root, 0, lengths)
+	return lengths, nil
+}
+
+func leafDepths(n *node, depth uint8, lengths map[string]uint8) {
+	if n.left == nil && n.right == nil {
+		for symb := range n.values {
+			lengths[symb] = depth
+		}
+		return
+	}
+	leafDepths(n.left, depth+1, lengths)
+	leafDepths(n.right, depth+1, lengths)
+}
+
+// EncodeTable возвращает канонические коды символов алфавита
+func (huff *HuffmanTree) EncodeTable() map[string]Code {
+	return CanonicalCodes(huff.CodeLengths())
+}
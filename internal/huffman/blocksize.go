@@ -0,0 +1,183 @@
+package huffman
+
+import (
+	"bytes"
+	alg "compressor/internal/huffman/algorithm"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+)
+
+// BlockStrategy задает способ выбора размера блока алфавита
+type BlockStrategy string
+
+const (
+	// BlockAuto оценивает кандидатов по всем данным, если их немного, иначе по выборке
+	BlockAuto BlockStrategy = "auto"
+	// BlockSample всегда оценивает кандидатов по выборке
+	BlockSample BlockStrategy = "sample"
+)
+
+const (
+	// sampleChunk делится на все размеры из blockCandidates,
+	// поэтому блоки внутри куска не обрываются
+	sampleChunk   = 96 << 10
+	sampleChunks  = 16
+	fullThreshold = 8 << 20
+)
+
+var blockCandidates = []int{1, 2, 3, 4, 6, 8, 12, 16, 24, 32}
+
+// BlockEstimate is the estimated output size for a block size.
+type BlockEstimate struct {
+	BlockSize int
+	Payload   int64
+	Table     int64
+	Symbols   int64 // estimated size of the alphabet
+}
+
+func (e BlockEstimate) Total() int64 { return e.Payload + e.Table }
+
+// SelectBlockSize tries the candidate block sizes on the input and returns
+// the one with the smallest estimated output. Block sizes whose alphabet
+// needs codes longer than maxCodeLen are skipped.
+func SelectBlockSize(pathes []string, strategy BlockStrategy, maxCodeLen uint8) (best BlockEstimate, err error) {
+	if maxCodeLen == 0 || maxCodeLen > MaxCodeLen {
+		return best, fmt.Errorf("max code length must be in range [1, %d]", MaxCodeLen)
+	}
+	if strategy != BlockAuto && strategy != BlockSample {
+		return best, fmt.Errorf("unknown block size strategy: %s", strategy)
+	}
+	files, err := utiles.OpenFiles(pathes...)
+	if err != nil {
+		return best, err
+	}
+	defer utiles.CloseFiles(files)
+
+	sizes := make([]int64, len(files))
+	var total int64
+	for i, f := range files {
+		info, err := f.Stat()
+		if err != nil {
+			return best, err
+		}
+		sizes[i] = info.Size()
+		total += sizes[i]
+	}
+	if total == 0 {
+		return BlockEstimate{BlockSize: minBlockSize}, nil
+	}
+
+	var chunks [][]byte
+	if strategy == BlockAuto && total <= fullThreshold {
+		chunks, err = readWhole(files, sizes)
+	} else {
+		chunks, err = readSample(files, sizes, total)
+	}
+	if err != nil {
+		return best, err
+	}
+
+	found := false
+	for _, blockSize := range blockCandidates {
+		estimate, err := estimateBlockSize(chunks, blockSize, total, maxCodeLen)
+		if err != nil {
+			return best, err
+		}
+		// при n символах самый длинный код не короче ceil(log2(n)) бит
+		if bits.Len64(uint64(max(estimate.Symbols, 1)-1)) > int(maxCodeLen) {
+			continue
+		}
+		if !found || estimate.Total() < best.Total() {
+			best, found = estimate, true
+		}
+	}
+	if !found {
+		return best, fmt.Errorf("max code length %d is too small for any block size", maxCodeLen)
+	}
+	return best, nil
+}
+
+func readWhole(files []*os.File, sizes []int64) ([][]byte, error) {
+	chunks := make([][]byte, len(files))
+	for i, f := range files {
+		chunks[i] = make([]byte, sizes[i])
+		if _, err := f.ReadAt(chunks[i], 0); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	return chunks, nil
+}
+
+// readSample читает sampleChunks кусков, равномерно распределенных по всем файлам
+func readSample(files []*os.File, sizes []int64, total int64) ([][]byte, error) {
+	chunks := make([][]byte, 0, sampleChunks)
+	step := max(total/sampleChunks, sampleChunk)
+	fileInd, fileStart := 0, int64(0)
+	for pos := int64(0); pos < total; pos += step {
+		for pos >= fileStart+sizes[fileInd] {
+			fileStart += sizes[fileInd]
+			fileInd++
+		}
+		offset := pos - fileStart
+		chunk := make([]byte, min(sampleChunk, sizes[fileInd]-offset))
+		if _, err := files[fileInd].ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+func estimateBlockSize(chunks [][]byte, blockSize int, total int64, maxCodeLen uint8) (BlockEstimate, error) {
+	freqs := make(map[string]uint64)
+	var sampled int64
+	for _, chunk := range chunks {
+		chunkFreqs, err := alg.CountFrequencies(bytes.NewReader(chunk), blockSize)
+		if err != nil {
+			return BlockEstimate{}, err
+		}
+		for symb, freq := range chunkFreqs {
+			freqs[symb] += freq
+		}
+		sampled += int64(len(chunk))
+	}
+
+	huff := alg.NewHuffmanTree(blockSize)
+	if err := huff.BuildTree(freqs); err != nil {
+		return BlockEstimate{}, err
+	}
+	lengths, err := huff.LimitedCodeLengths(maxCodeLen)
+	if err != nil {
+		// алфавит выборки не помещается в коды maxCodeLen, размер блока отбрасывается
+		return BlockEstimate{BlockSize: blockSize, Symbols: int64(len(freqs))}, nil
+	}
+
+	var bits, blocks, singletons uint64
+	for symb, freq := range freqs {
+		bits += uint64(lengths[symb]) * freq
+		blocks += freq
+		if freq == 1 {
+			singletons++
+		}
+	}
+	scale := float64(total) / float64(sampled)
+
+	// Символы, встреченные в выборке один раз, показывают, как часто
+	// в оставшихся данных будут появляться новые символы (оценка Гуда-Тьюринга)
+	distinct := float64(len(freqs))
+	if scale > 1 {
+		unseen := float64(total)/float64(blockSize) - float64(blocks)
+		distinct += float64(singletons) / float64(blocks) * max(unseen, 0)
+	}
+
+	return BlockEstimate{
+		BlockSize: blockSize,
+		Payload:   int64(math.Ceil(float64(bits) / 8 * scale)),
+		Table:     int64(distinct * float64(blockSize+1)),
+		Symbols:   int64(math.Ceil(distinct)),
+	}, nil
+}
@@ -0,0 +1,58 @@
+package huffman
+
+import (
+	comp "compressor/internal/compressing"
+	"compressor/internal/store"
+	"compressor/internal/utiles"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Случайный файл при мелком блоке дает выигрыш по данным, но раздувает
+// таблицу кодов. Он должен уйти в STORE, не забирая с собой текст.
+func TestRejectIncompressible(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "src")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	random := make([]byte, 200_000)
+	rand.New(rand.NewSource(2)).Read(random)
+	files := map[string][]byte{
+		"text.txt": textData(90_000),
+		"rand.bin": random,
+	}
+	var pathes []string
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		pathes = append(pathes, path)
+	}
+
+	c := NewCompressor(3, 0)
+	comps := []comp.CompressionBase{c, c}
+	dst, err := os.Create(filepath.Join(t.TempDir(), "out.dedal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	prog := utiles.NewProgress[int64](0)
+	prog.Close()
+	if _, _, err := comp.CompressFilesWith(comps, store.NewCompressor(), pathes, dst, prog); err != nil {
+		t.Fatal(err)
+	}
+
+	md, _, err := comp.ReadFooterMetadata(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"text.txt": CompressionType, "rand.bin": store.CompressionType}
+	for _, f := range md.FileMap {
+		if got := md.Codecs[f.Codec].Type; got != want[f.Path] {
+			t.Errorf("%s: got codec %s, want %s", f.Path, got, want[f.Path])
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package huffman
+
+import (
+	alg "compressor/internal/huffman/algorithm"
+	"compressor/internal/utiles"
+	"io"
+)
+
+// lookupBits — число бит, по которым за один шаг ищется символ в таблице.
+// Более длинные коды декодируются через вложенные таблицы.
+const lookupBits = 10
+
+// progressStep — прогресс сообщается не чаще, чем раз в столько байт
+const progressStep = 64 << 10
+
+type decodeEntry struct {
+	symbol int32 // index in decoder.symbols, -1 for an empty entry
+	length uint8 // bits of the code consumed at this level
+	sub    *decodeTable
+}
+
+type decodeTable struct {
+	bits    uint8
+	entries []decodeEntry
+}
+
+// decoder декодирует коды по таблицам префиксов вместо побитового обхода дерева
+type decoder struct {
+	root    *decodeTable
+	symbols [][]byte
+}
+
+type tableCode struct {
+	bits   uint64
+	length uint8
+	symbol int32
+}
+
+func newDecoder(codes map[string]alg.Code) *decoder {
+	d := &decoder{symbols: make([][]byte, 0, len(codes))}
+	list := make([]tableCode, 0, len(codes))
+	for symb, code := range codes {
+		list = append(list, tableCode{code.Bits, code.Len, int32(len(d.symbols))})
+		d.symbols = append(d.symbols, []byte(symb))
+	}
+	d.root = newDecodeTable(list)
+	return d
+}
+
+// newDecodeTable строит таблицу по кодам, у которых уже отброшены
+// биты, использованные таблицами верхних уровней
+func newDecodeTable(codes []tableCode) *decodeTable {
+	var maxLen uint8
+	for _, c := range codes {
+		maxLen = max(maxLen, c.length)
+	}
+	t := &decodeTable{bits: min(maxLen, lookupBits)}
+	t.entries = make([]decodeEntry, 1<<t.bits)
+	for i := range t.entries {
+		t.entries[i].symbol = -1
+	}
+
+	long := make(map[uint64][]tableCode)
+	for _, c := range codes {
+		if c.length <= t.bits {
+			// код занимает все записи, у которых он является префиксом
+			shift := t.bits - c.length
+			first := c.bits << shift
+			for i := first; i < first+(1<<shift); i++ {
+				t.entries[i] = decodeEntry{symbol: c.symbol, length: c.length}
+			}
+			continue
+		}
+		rest := c.length - t.bits
+		prefix := c.bits >> rest
+		long[prefix] = append(long[prefix], tableCode{c.bits & (1<<rest - 1), rest, c.symbol})
+	}
+	for prefix, subCodes := range long {
+		t.entries[prefix] = decodeEntry{symbol: -1, length: t.bits, sub: newDecodeTable(subCodes)}
+	}
+	return t
+}
+
+// next декодирует очередной символ
+func (d *decoder) next(br *utiles.BitReader) ([]byte, error) {
+	var read alg.Code
+	t := d.root
+	for {
+		bits, err := br.PeekBits(t.bits)
+		if err != nil {
+			return nil, err
+		}
+		e := &t.entries[bits]
+		if e.sub == nil && e.symbol < 0 || int64(e.length) > br.BitsLeft() {
+			read.Bits, read.Len = read.Bits<<t.bits|bits, read.Len+t.bits
+			return nil, &ErrNoSymbol{read}
+		}
+		if err := br.SkipBits(e.length); err != nil {
+			return nil, err
+		}
+		if e.sub == nil {
+			return d.symbols[e.symbol], nil
+		}
+		read.Bits, read.Len = read.Bits<<t.bits|bits, read.Len+t.bits
+		t = e.sub
+	}
+}
+
+// decode декодирует весь поток br в dst
+func (d *decoder) decode(br *utiles.BitReader, dst io.Writer, prog *utiles.Progress[int64]) error {
+	total := br.BitsLeft()
+	var reported int64
+	for br.BitsLeft() > 0 {
+		symbol, err := d.next(br)
+		if err != nil {
+			return err
+		}
+		if _, err := dst.Write(symbol); err != nil {
+			return err
+		}
+		if consumed := (total - br.BitsLeft()) / 8; consumed-reported >= progressStep {
+			prog.Write(consumed - reported)
+			reported = consumed
+		}
+	}
+	if consumed := (total + 7) / 8; consumed > reported {
+		prog.Write(consumed - reported)
+	}
+	return nil
+}
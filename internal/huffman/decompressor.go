@@ -1,111 +1,107 @@
-package huffman
-
-import (
-	comp "compressor/internal/compressing"
-	"compressor/internal/utiles"
-	"encoding/binary"
-	"fmt"
-	"io"
-	"os"
-	"slices"
-)
-
-type ErrNoSymbol struct{ code []byte }
-
-func (e *ErrNoSymbol) Error() string {
-	return fmt.Sprintf("The code %s doesn't match any symbol", utiles.HexBytes(e.code))
-}
-
-type Decompressor struct{}
-
-func NewDecompressor() *Decompressor { return &Decompressor{} }
-
-func (d *Decompressor) FooterBodyType() comp.Body {
-	m := make(map[string][]byte)
-	return &m
-}
-
-func (d *Decompressor) Preprocessing(_ comp.Body, _ io.ReadSeeker) error { return nil }
-
-func (d *Decompressor) DecompressFile(dd *comp.DecompressionInput, prog *utiles.Progress[int64]) error {
-	src, dst := dd.SourceFile, dd.DestFile
-
-	codes := *(dd.Body.(*map[string][]byte))
-	// showFooterData(codes)
-	codesToSymbols := make(map[string][]byte)
-	minCodeLen, maxCodeLen := (1<<32)-1, 0
-	for symb, code := range codes {
-		codesToSymbols[string(code)] = []byte(symb)
-		minCodeLen = min(minCodeLen, len(code))
-		maxCodeLen = max(maxCodeLen, len(code))
-	}
-
-	buf := make([]byte, maxCodeLen)
-	for {
-		var matched []byte
-		found := false
-
-		if err := binary.Read(src, binary.NativeEndian, buf[:minCodeLen-1]); err != nil {
-			if err == io.EOF || err == io.ErrUnexpectedEOF {
-				return nil
-			}
-			return err
-		}
-		var newByte byte
-		for i := minCodeLen - 1; i < maxCodeLen; i++ {
-			if err := binary.Read(src, binary.NativeEndian, &newByte); err != nil {
-				if err == io.EOF || err == io.ErrUnexpectedEOF {
-					return nil
-				}
-				return err
-			}
-
-			buf[i] = newByte
-			if symb, ok := codesToSymbols[string(buf[:i+1])]; ok {
-				matched, found = symb, true
-				break
-			}
-		}
-		if !found {
-			return &ErrNoCode{buf}
-		}
-		if _, err := dst.Write(matched); err != nil {
-			return err
-		}
-		clear(buf)
-		prog.Write(int64(len(matched)))
-	}
-}
-
-func showFooterData(codes map[string][]byte) {
-	titles := []string{"symbol", "symbol bytes", "code bytes", "bin code"}
-	rows := make([][]string, 0, len(codes))
-	for symb, code := range codes {
-		row := []string{
-			fmt.Sprintf("%q", symb),
-			utiles.HexBytes([]byte(symb)),
-			utiles.HexBytes(code),
-			utiles.BinBytes(code),
-		}
-		rows = append(rows, row)
-	}
-	slices.SortFunc(rows, func(row1, row2 []string) int {
-		if row1[1] > row2[1] {
-			return 1
-		}
-		if row1[1] < row2[1] {
-			return -1
-		}
-		return 0
-	})
-	t, _ := os.Create("Table.txt")
-	defer t.Close()
-	p := utiles.TableParams{
-		ColSep:      "",
-		RowSep:      "",
-		VerticalSep: true,
-		IndentSize:  0,
-		Writer:      t,
-	}
-	utiles.ShowTable(titles, rows, p)
-}
+package huffman
+
+import (
+	"bufio"
+	comp "compressor/internal/compressing"
+	alg "compressor/internal/huffman/algorithm"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+type ErrNoSymbol struct{ code alg.Code }
+
+func (e *ErrNoSymbol) Error() string {
+	return fmt.Sprintf("The code %0*b doesn't match any symbol", e.code.Len, e.code.Bits)
+}
+
+type Decompressor struct {
+	version int
+	dec     *decoder
+	legacy  *legacyDecoder
+}
+
+func NewDecompressor(version int) *Decompressor { return &Decompressor{version: version} }
+
+func (d *Decompressor) FooterBodyType() comp.Body {
+	if d.version == legacyVersion {
+		m := make(map[string][]byte)
+		return &m
+	}
+	return &Table{}
+}
+
+func (d *Decompressor) Preprocessing(data comp.Body, _ io.ReadSeeker) error {
+	var codes map[string]alg.Code
+	switch body := data.(type) {
+	case *map[string][]byte:
+		legacy, err := newLegacyDecoder(*body)
+		if err != nil {
+			return err
+		}
+		d.legacy = legacy
+		return nil
+	case *Table:
+		lengths, err := body.codeLengths()
+		if err != nil {
+			return err
+		}
+		codes = alg.CanonicalCodes(lengths)
+	default:
+		return fmt.Errorf("unexpected footer body %T", data)
+	}
+	// showFooterData(codes)
+	d.dec = newDecoder(codes)
+	return nil
+}
+
+func (d *Decompressor) DecompressFile(dd *comp.DecompressionInput, prog *utiles.Progress[int64]) error {
+	if d.legacy != nil {
+		dst := bufio.NewWriter(dd.DestFile)
+		if err := d.legacy.decode(dd.SourceFile, dst, prog); err != nil {
+			return err
+		}
+		return dst.Flush()
+	}
+	br := utiles.NewBitReader(dd.SourceFile, dd.Entry.BitSize())
+	dst := bufio.NewWriter(dd.DestFile)
+	if err := d.dec.decode(br, dst, prog); err != nil {
+		return err
+	}
+	return dst.Flush()
+}
+
+func showFooterData(codes map[string]alg.Code) {
+	titles := []string{"symbol", "symbol bytes", "code length", "bin code"}
+	rows := make([][]string, 0, len(codes))
+	for symb, code := range codes {
+		row := []string{
+			fmt.Sprintf("%q", symb),
+			utiles.HexBytes([]byte(symb)),
+			fmt.Sprintf("%d", code.Len),
+			fmt.Sprintf("%0*b", code.Len, code.Bits),
+		}
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(row1, row2 []string) int {
+		if row1[1] > row2[1] {
+			return 1
+		}
+		if row1[1] < row2[1] {
+			return -1
+		}
+		return 0
+	})
+	t, _ := os.Create("Table.txt")
+	defer t.Close()
+	p := utiles.TableParams{
+		ColSep:      "",
+		RowSep:      "",
+		VerticalSep: true,
+		IndentSize:  0,
+		Writer:      t,
+	}
+	utiles.ShowTable(titles, rows, p)
+}
@@ -0,0 +1,167 @@
+package huffman
+
+import (
+	"bytes"
+	comp "compressor/internal/compressing"
+	alg "compressor/internal/huffman/algorithm"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// textData генерирует текст из слов с неравномерными частотами
+func textData(size int) []byte {
+	words := []string{
+		"the", "archive", "compression", "of", "files", "and", "a", "huffman",
+		"code", "table", "is", "stored", "in", "footer", "block", "size", "\n",
+	}
+	rnd := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for buf.Len() < size {
+		buf.WriteString(words[int(rnd.ExpFloat64()*3)%len(words)])
+		buf.WriteByte(' ')
+	}
+	return buf.Bytes()[:size]
+}
+
+type compressed struct {
+	body    comp.Body
+	codes   map[string]alg.Code
+	payload []byte
+	entry   comp.File
+}
+
+func compress(tb testing.TB, data []byte, blockSize int) *compressed {
+	tb.Helper()
+	c := NewCompressor(blockSize, int64(len(data)))
+	if _, err := c.Preprocessing([]io.Reader{bytes.NewReader(data)}); err != nil {
+		tb.Fatal(err)
+	}
+	prog := utiles.NewProgress[int64](0)
+	prog.Close()
+
+	var payload bytes.Buffer
+	size, trailingBits, err := c.CompressFile(bytes.NewReader(data), &payload, prog)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	_, body := c.CompressorData()
+	return &compressed{
+		body:    body,
+		codes:   c.codes,
+		payload: payload.Bytes(),
+		entry:   comp.File{Size: size, TrailingBits: trailingBits},
+	}
+}
+
+func decompress(tb testing.TB, d *Decompressor, c *compressed, dst io.Writer) {
+	tb.Helper()
+	prog := utiles.NewProgress[int64](0)
+	prog.Close()
+
+	input := &comp.DecompressionInput{
+		Body:       c.body,
+		SourceFile: bytes.NewReader(c.payload),
+		DestFile:   dst,
+		Entry:      c.entry,
+	}
+	if err := d.DecompressFile(input, prog); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// legacyPayload кодирует data, как исходная версия компрессора: код каждого
+// блока дополняется нулями до целого числа байт и пишется отдельно
+func legacyPayload(codes map[string]alg.Code, data []byte, blockSize int) (map[string][]byte, []byte) {
+	padded := make(map[string][]byte, len(codes))
+	for symb, code := range codes {
+		b := make([]byte, (int(code.Len)+7)/8)
+		for i := 0; i < int(code.Len); i++ {
+			if code.Bits>>(int(code.Len)-1-i)&1 == 1 {
+				b[i/8] |= 1 << (7 - i%8)
+			}
+		}
+		padded[symb] = b
+	}
+	var payload []byte
+	for i := 0; i < len(data); i += blockSize {
+		payload = append(payload, padded[string(data[i:min(i+blockSize, len(data))])]...)
+	}
+	return padded, payload
+}
+
+func TestDecompressFile(t *testing.T) {
+	cases := map[string][]byte{
+		"empty":  {},
+		"single": bytes.Repeat([]byte{'a'}, 100),
+		"text":   textData(100000),
+		"random": func() []byte {
+			data := make([]byte, 50000)
+			rand.New(rand.NewSource(2)).Read(data)
+			return data
+		}(),
+	}
+	for name, data := range cases {
+		for _, blockSize := range []int{1, 2, 3, 16} {
+			t.Run(fmt.Sprintf("%s/block=%d", name, blockSize), func(t *testing.T) {
+				c := compress(t, data, blockSize)
+
+				d := NewDecompressor(canonicalVersion)
+				if err := d.Preprocessing(c.body, nil); err != nil {
+					t.Fatal(err)
+				}
+				var out bytes.Buffer
+				decompress(t, d, c, &out)
+				if !bytes.Equal(out.Bytes(), data) {
+					t.Fatalf("decompressed data differs from the original")
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkDecompress(b *testing.B) {
+	data := textData(4 << 20)
+	for _, blockSize := range []int{1, 4} {
+		c := compress(b, data, blockSize)
+
+		// до табличного декодера архивы читались по байтам с поиском кода в map
+		b.Run(fmt.Sprintf("legacy/block=%d", blockSize), func(b *testing.B) {
+			codes, payload := legacyPayload(c.codes, data, blockSize)
+			d, err := newLegacyDecoder(codes)
+			if err != nil {
+				b.Fatal(err)
+			}
+			prog := utiles.NewProgress[int64](0)
+			prog.Close()
+			var out bytes.Buffer
+			if err := d.decode(bytes.NewReader(payload), &out, prog); err != nil {
+				b.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), data) {
+				b.Fatal("legacy payload is decoded wrong")
+			}
+
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := d.decode(bytes.NewReader(payload), io.Discard, prog); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("table/block=%d", blockSize), func(b *testing.B) {
+			d := NewDecompressor(canonicalVersion)
+			if err := d.Preprocessing(c.body, nil); err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				decompress(b, d, c, io.Discard)
+			}
+		})
+	}
+}
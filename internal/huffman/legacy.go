@@ -0,0 +1,76 @@
+package huffman
+
+import (
+	"bufio"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+)
+
+// ErrNoLegacySymbol is returned when bytes of an archive written before
+// codesVersion don't start with any code of its table.
+type ErrNoLegacySymbol struct{ code []byte }
+
+func (e *ErrNoLegacySymbol) Error() string {
+	return fmt.Sprintf("The code %s doesn't match any symbol", utiles.HexBytes(e.code))
+}
+
+// legacyDecoder декодирует архивы первых версий: тело футера — gob
+// map[string][]byte, каждый код дополнен до целого числа байт и пишется
+// отдельно. Код ищется по самому короткому совпавшему префиксу, как
+// делал исходный декодер.
+type legacyDecoder struct {
+	symbols        map[string][]byte // код -> символ
+	minLen, maxLen int
+}
+
+func newLegacyDecoder(codes map[string][]byte) (*legacyDecoder, error) {
+	d := &legacyDecoder{symbols: make(map[string][]byte, len(codes)), minLen: -1}
+	for symb, code := range codes {
+		if len(code) == 0 {
+			return nil, fmt.Errorf("empty code of symbol %q", symb)
+		}
+		d.symbols[string(code)] = []byte(symb)
+		if d.minLen < 0 || len(code) < d.minLen {
+			d.minLen = len(code)
+		}
+		d.maxLen = max(d.maxLen, len(code))
+	}
+	return d, nil
+}
+
+// decode декодирует весь поток src в dst
+func (d *legacyDecoder) decode(src io.Reader, dst io.Writer, prog *utiles.Progress[int64]) error {
+	r := bufio.NewReader(src)
+	buf := make([]byte, 0, d.maxLen)
+	var consumed, reported int64
+	for {
+		buf = buf[:0]
+		var symbol []byte
+		for symbol == nil {
+			b, err := r.ReadByte()
+			if err == io.EOF && len(buf) == 0 {
+				prog.Write(consumed - reported)
+				return nil
+			} else if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			} else if err != nil {
+				return err
+			}
+			buf = append(buf, b)
+			if len(buf) >= d.minLen {
+				symbol = d.symbols[string(buf)]
+			}
+			if symbol == nil && len(buf) == d.maxLen {
+				return &ErrNoLegacySymbol{buf}
+			}
+		}
+		if _, err := dst.Write(symbol); err != nil {
+			return err
+		}
+		if consumed += int64(len(buf)); consumed-reported >= progressStep {
+			prog.Write(consumed - reported)
+			reported = consumed
+		}
+	}
+}
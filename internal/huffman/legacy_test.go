@@ -0,0 +1,64 @@
+package huffman
+
+import (
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testdata/baseline.dedal записан исходной версией компрессора (без
+// заголовка, gob-футер, коды дополнены до байт) из таких файлов:
+//
+//	a.txt        legacyText
+//	empty.txt    пустой
+//	sub/nums.txt вывод seq 1 300
+const legacyText = "hello legacy huffman archive\nthe quick brown fox jumps over the lazy dog\n"
+
+func legacyNums() string {
+	var b strings.Builder
+	for i := 1; i <= 300; i++ {
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	return b.String()
+}
+
+func TestDecompressBaselineArchive(t *testing.T) {
+	src, err := os.Open(filepath.Join("testdata", "baseline.dedal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	factory := func(compType string, version int) comp.Decompressor {
+		if compType != CompressionType {
+			return nil
+		}
+		return NewDecompressor(version)
+	}
+	prog := utiles.NewProgress[int64](0)
+	prog.Close()
+
+	dst := t.TempDir()
+	if _, err := comp.Decompress(factory, src, dst, comp.DecompressOptions{}, prog); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"a.txt":        legacyText,
+		"empty.txt":    "",
+		"sub/nums.txt": legacyNums(),
+	}
+	for path, content := range want {
+		got, err := os.ReadFile(filepath.Join(dst, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s: got %q, want %q", path, got, content)
+		}
+	}
+}
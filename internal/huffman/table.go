@@ -0,0 +1,95 @@
+package huffman
+
+import (
+	comp "compressor/internal/compressing"
+	alg "compressor/internal/huffman/algorithm"
+	"fmt"
+	"slices"
+)
+
+const (
+	// legacyVersion — архивы до канонических кодов: тело футера хранит коды
+	// целиком (map[string][]byte), каждый код дополнен до целого байта
+	legacyVersion = 0
+	// canonicalVersion — тело футера хранит только длины канонических кодов
+	canonicalVersion = 1
+)
+
+// Table is the footer body of the canonical format: symbols of the alphabet
+// and their code lengths. Tails are the symbols shorter than BlockSize,
+// which only occur at the ends of files.
+type Table struct {
+	BlockSize   int
+	Symbols     []byte  // concatenated symbols of BlockSize bytes
+	Lengths     []uint8 // code lengths of Symbols
+	Tails       [][]byte
+	TailLengths []uint8
+}
+
+func newTable(blockSize int, lengths map[string]uint8) *Table {
+	symbols := make([]string, 0, len(lengths))
+	for symb := range lengths {
+		symbols = append(symbols, symb)
+	}
+	slices.Sort(symbols)
+
+	t := &Table{BlockSize: blockSize}
+	for _, symb := range symbols {
+		if len(symb) == blockSize {
+			t.Symbols = append(t.Symbols, symb...)
+			t.Lengths = append(t.Lengths, lengths[symb])
+		} else {
+			t.Tails = append(t.Tails, []byte(symb))
+			t.TailLengths = append(t.TailLengths, lengths[symb])
+		}
+	}
+	return t
+}
+
+// EncodeBody пишет размер блока, символы и длины их кодов, затем хвосты
+// с длинами кодов.
+func (t *Table) EncodeBody(e *comp.Encoder) {
+	e.Uvarint(uint64(t.BlockSize))
+	e.Blob(t.Symbols)
+	e.Blob(t.Lengths)
+	e.Uvarint(uint64(len(t.Tails)))
+	for i, tail := range t.Tails {
+		e.Blob(tail)
+		e.Byte(t.TailLengths[i])
+	}
+}
+
+func (t *Table) DecodeBody(d *comp.Decoder) error {
+	t.BlockSize = d.Int()
+	t.Symbols = d.Blob()
+	t.Lengths = d.Blob()
+	n := d.Count(2)
+	t.Tails = make([][]byte, n)
+	t.TailLengths = make([]uint8, n)
+	for i := range n {
+		t.Tails[i] = d.Blob()
+		t.TailLengths[i] = d.Byte()
+	}
+	return d.Err()
+}
+
+func (t *Table) codeLengths() (map[string]uint8, error) {
+	if t.BlockSize <= 0 || len(t.Symbols) != len(t.Lengths)*t.BlockSize {
+		return nil, fmt.Errorf("malformed code table")
+	}
+	if len(t.Tails) != len(t.TailLengths) {
+		return nil, fmt.Errorf("malformed code table")
+	}
+
+	lengths := make(map[string]uint8, len(t.Lengths)+len(t.Tails))
+	for i, length := range t.Lengths {
+		lengths[string(t.Symbols[i*t.BlockSize:(i+1)*t.BlockSize])] = length
+	}
+	for i, tail := range t.Tails {
+		lengths[string(tail)] = t.TailLengths[i]
+	}
+	if err := alg.ValidateLengths(lengths); err != nil {
+		return nil, err
+	}
+	return lengths, nil
+}
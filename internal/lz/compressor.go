@@ -0,0 +1,121 @@
+package lz
+
+import (
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+const (
+	CompressionType = "LZSS"
+
+	DefaultWindowSize = 32 << 10
+	MinWindowSize     = 1 << 8
+	MaxWindowSize     = 1 << 24
+
+	lengthBits      = 8
+	defaultMaxChain = 128
+	progressStep    = 64 << 10
+)
+
+// Params is the footer body: parameters needed to decode the stream.
+type Params struct {
+	WindowBits uint8
+	LengthBits uint8
+}
+
+func (p *Params) EncodeBody(e *comp.Encoder) {
+	e.Byte(p.WindowBits)
+	e.Byte(p.LengthBits)
+}
+
+func (p *Params) DecodeBody(d *comp.Decoder) error {
+	p.WindowBits, p.LengthBits = d.Byte(), d.Byte()
+	return d.Err()
+}
+
+// Compressor кодирует файлы как последовательность литералов и ссылок
+// (смещение, длина) на уже закодированные данные в пределах окна.
+//
+// Каждый элемент начинается с флага: 1 — литерал (8 бит),
+// 0 — ссылка (WindowBits бит смещения-1 и LengthBits бит длины-minMatch).
+type Compressor struct {
+	WindowSize int
+	MaxChain   int
+}
+
+func NewCompressor(windowSize int) (*Compressor, error) {
+	if err := validateWindow(windowSize); err != nil {
+		return nil, err
+	}
+	return &Compressor{windowSize, defaultMaxChain}, nil
+}
+
+func validateWindow(windowSize int) error {
+	if windowSize < MinWindowSize || windowSize > MaxWindowSize || windowSize&(windowSize-1) != 0 {
+		return fmt.Errorf(
+			"window size must be a power of two in range [%d, %d]: %d",
+			MinWindowSize, MaxWindowSize, windowSize,
+		)
+	}
+	return nil
+}
+
+func (c *Compressor) windowBits() uint8 { return uint8(bits.TrailingZeros(uint(c.WindowSize))) }
+
+func (c *Compressor) Preprocessing(_ []io.Reader) error { return nil }
+
+func (c *Compressor) CompressorData() (string, comp.Body) {
+	return CompressionType, &Params{c.windowBits(), lengthBits}
+}
+
+func (c *Compressor) CompressFile(
+	src io.Reader, dst io.Writer, prog *utiles.Progress[int64],
+) (size int64, trailingBits uint8, err error) {
+	m := newMatcher(src, c.WindowSize, c.MaxChain)
+	bw := utiles.NewBitWriter(dst)
+	windowBits := c.windowBits()
+
+	var pos, reported int64
+	for {
+		if err := m.fill(pos); err != nil {
+			return 0, 0, err
+		}
+		if pos >= m.end() {
+			break
+		}
+
+		offset, length := m.find(pos)
+		if length >= minMatch {
+			if err := bw.WriteBits(0, 1); err != nil {
+				return 0, 0, err
+			}
+			if err := bw.WriteBits(uint64(offset-1), windowBits); err != nil {
+				return 0, 0, err
+			}
+			if err := bw.WriteBits(uint64(length-minMatch), lengthBits); err != nil {
+				return 0, 0, err
+			}
+		} else {
+			length = 1
+			if err := bw.WriteBits(1<<8|uint64(m.at(pos)), 9); err != nil {
+				return 0, 0, err
+			}
+		}
+		for i := int64(0); i < int64(length); i++ {
+			m.insert(pos + i)
+		}
+		pos += int64(length)
+
+		if pos-reported >= progressStep {
+			prog.Write(pos - reported)
+			reported = pos
+		}
+	}
+	if pos > reported {
+		prog.Write(pos - reported)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,141 @@
+package lz
+
+import (
+	"bytes"
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"math/rand"
+	"testing"
+)
+
+type token struct{ offset, length int }
+
+// roundTrip сжимает data, проверяет распаковку и возвращает ссылки потока
+func roundTrip(t *testing.T, data []byte, window int) []token {
+	t.Helper()
+	c, err := NewCompressor(window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := utiles.NewProgress[int64](0)
+	prog.Close()
+
+	var payload bytes.Buffer
+	size, trailingBits, err := c.CompressFile(bytes.NewReader(data), &payload, prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := comp.File{Size: size, TrailingBits: trailingBits}
+	_, body := c.CompressorData()
+
+	d := NewDecompressor()
+	if err := d.Preprocessing(body, nil); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	input := &comp.DecompressionInput{Body: body, SourceFile: bytes.NewReader(payload.Bytes()), DestFile: &out, Entry: entry}
+	if err := d.DecompressFile(input, prog); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("got %d bytes, want %d: data differs", out.Len(), len(data))
+	}
+	return tokens(t, payload.Bytes(), entry, c.windowBits())
+}
+
+// tokens разбирает поток и возвращает его ссылки
+func tokens(t *testing.T, payload []byte, entry comp.File, windowBits uint8) []token {
+	t.Helper()
+	br := utiles.NewBitReader(bytes.NewReader(payload), entry.BitSize())
+	var refs []token
+	for br.BitsLeft() > 0 {
+		flag, err := br.ReadBit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if flag == 1 {
+			if _, err := br.ReadBits(8); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		offset, err := br.ReadBits(windowBits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		length, err := br.ReadBits(lengthBits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs = append(refs, token{int(offset) + 1, int(length) + minMatch})
+	}
+	return refs
+}
+
+func TestRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	block := make([]byte, MinWindowSize)
+	rnd.Read(block)
+	// мало различных байт — много совпадений на разных расстояниях
+	small := make([]byte, 20_000)
+	for i := range small {
+		small[i] = "abcd"[rnd.Intn(4)]
+	}
+	random := make([]byte, 5_000)
+	rnd.Read(random)
+
+	cases := []struct {
+		name   string
+		data   []byte
+		window int
+		want   func(refs []token) bool // нужная ссылка есть в потоке
+	}{
+		{"empty", nil, DefaultWindowSize, nil},
+		{"one byte", []byte{7}, DefaultWindowSize, nil},
+		{"min match", []byte("abcXabc"), DefaultWindowSize, func(refs []token) bool {
+			return len(refs) == 1 && refs[0] == token{4, minMatch}
+		}},
+		{"max match", bytes.Repeat([]byte{'a'}, 3*maxMatch), DefaultWindowSize, func(refs []token) bool {
+			return hasToken(refs, func(r token) bool { return r.length == maxMatch })
+		}},
+		{"window edge", append(append([]byte{}, block...), block...), MinWindowSize, func(refs []token) bool {
+			return hasToken(refs, func(r token) bool { return r.offset == MinWindowSize })
+		}},
+		{"past window edge", append(append(append([]byte{}, block...), 0), block...), MinWindowSize, func(refs []token) bool {
+			return !hasToken(refs, func(r token) bool { return r.length > minMatch+10 })
+		}},
+		{"small alphabet min window", small, MinWindowSize, nil},
+		{"small alphabet", small, DefaultWindowSize, nil},
+		{"random", random, MinWindowSize, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			refs := roundTrip(t, c.data, c.window)
+			for _, r := range refs {
+				if r.offset > c.window || r.length < minMatch || r.length > maxMatch {
+					t.Fatalf("invalid match %+v for window %d", r, c.window)
+				}
+			}
+			if c.want != nil && !c.want(refs) {
+				t.Fatalf("no expected match in %v", refs)
+			}
+		})
+	}
+}
+
+func hasToken(refs []token, ok func(token) bool) bool {
+	for _, r := range refs {
+		if ok(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWindowSize(t *testing.T) {
+	for _, window := range []int{MinWindowSize / 2, MinWindowSize + 1, MaxWindowSize * 2} {
+		if _, err := NewCompressor(window); err == nil {
+			t.Errorf("window %d was accepted", window)
+		}
+	}
+}
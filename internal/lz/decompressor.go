@@ -0,0 +1,101 @@
+package lz
+
+import (
+	"bufio"
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+)
+
+type ErrBadOffset struct{ offset, written int64 }
+
+func (e *ErrBadOffset) Error() string {
+	return fmt.Sprintf("Match offset %d points before the start of the file (%d bytes written)", e.offset, e.written)
+}
+
+type Decompressor struct {
+	params *Params
+}
+
+func NewDecompressor() *Decompressor { return &Decompressor{} }
+
+func (d *Decompressor) FooterBodyType() comp.Body { return &Params{} }
+
+func (d *Decompressor) Preprocessing(data comp.Body, _ io.ReadSeeker) error {
+	params, ok := data.(*Params)
+	if !ok {
+		return fmt.Errorf("unexpected footer body %T", data)
+	}
+	if err := validateWindow(1 << params.WindowBits); err != nil {
+		return err
+	}
+	if params.LengthBits == 0 || params.LengthBits > 16 {
+		return fmt.Errorf("invalid match length size: %d bits", params.LengthBits)
+	}
+	d.params = params
+	return nil
+}
+
+func (d *Decompressor) DecompressFile(dd *comp.DecompressionInput, prog *utiles.Progress[int64]) error {
+	windowBits, lengthBits := d.params.WindowBits, d.params.LengthBits
+	window := int64(1) << windowBits
+	// history — кольцевой буфер последних window байт вывода
+	history := make([]byte, window)
+	mask := window - 1
+
+	total := dd.Entry.BitSize()
+	br := utiles.NewBitReader(dd.SourceFile, total)
+	dst := bufio.NewWriter(dd.DestFile)
+
+	var written, reported int64
+	for br.BitsLeft() > 0 {
+		flag, err := br.ReadBit()
+		if err != nil {
+			return err
+		}
+		if flag == 1 {
+			literal, err := br.ReadBits(8)
+			if err != nil {
+				return err
+			}
+			history[written&mask] = byte(literal)
+			if err := dst.WriteByte(byte(literal)); err != nil {
+				return err
+			}
+			written++
+		} else {
+			offset, err := br.ReadBits(windowBits)
+			if err != nil {
+				return err
+			}
+			length, err := br.ReadBits(lengthBits)
+			if err != nil {
+				return err
+			}
+			offset, length = offset+1, length+minMatch
+			if int64(offset) > written {
+				return &ErrBadOffset{int64(offset), written}
+			}
+			// источник может перекрываться с выводом, поэтому копируем побайтно
+			from := written - int64(offset)
+			for i := int64(0); i < int64(length); i++ {
+				b := history[(from+i)&mask]
+				history[(written+i)&mask] = b
+				if err := dst.WriteByte(b); err != nil {
+					return err
+				}
+			}
+			written += int64(length)
+		}
+
+		if consumed := (total - br.BitsLeft()) / 8; consumed-reported >= progressStep {
+			prog.Write(consumed - reported)
+			reported = consumed
+		}
+	}
+	if consumed := (total + 7) / 8; consumed > reported {
+		prog.Write(consumed - reported)
+	}
+	return dst.Flush()
+}
@@ -0,0 +1,115 @@
+package lz
+
+import "io"
+
+const (
+	minMatch  = 3
+	maxMatch  = minMatch + 1<<lengthBits - 1
+	hashBits  = 15
+	hashMul   = 0x9E3779B1
+	noPos     = -1
+	readChunk = 64 << 10
+)
+
+// matcher ищет совпадения в скользящем окне с помощью хэш-цепочек:
+// head хранит последнюю позицию для хэша трех байт, prev — предыдущую
+// позицию с тем же хэшем для каждой позиции окна.
+type matcher struct {
+	src      io.Reader
+	window   int
+	maxChain int
+
+	buf  []byte // данные, начиная с абсолютной позиции base
+	base int64
+	eof  bool
+
+	head []int64
+	prev []int64
+}
+
+func newMatcher(src io.Reader, window, maxChain int) *matcher {
+	m := &matcher{
+		src:      src,
+		window:   window,
+		maxChain: maxChain,
+		buf:      make([]byte, 0, 2*window+maxMatch+readChunk),
+		head:     make([]int64, 1<<hashBits),
+		prev:     make([]int64, window),
+	}
+	for i := range m.head {
+		m.head[i] = noPos
+	}
+	return m
+}
+
+// fill дочитывает данные так, чтобы после pos было не меньше maxMatch байт,
+// и сдвигает буфер, когда начало окна уходит далеко от его начала
+func (m *matcher) fill(pos int64) error {
+	for !m.eof && m.base+int64(len(m.buf)) < pos+maxMatch {
+		if keep := pos - int64(m.window) - m.base; keep > int64(m.window) {
+			n := copy(m.buf, m.buf[keep:])
+			m.buf = m.buf[:n]
+			m.base += keep
+		}
+		free := m.buf[len(m.buf):min(cap(m.buf), len(m.buf)+readChunk)]
+		n, err := io.ReadFull(m.src, free)
+		m.buf = m.buf[:len(m.buf)+n]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			m.eof = true
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// end возвращает абсолютную позицию конца прочитанных данных
+func (m *matcher) end() int64 { return m.base + int64(len(m.buf)) }
+
+func (m *matcher) at(pos int64) byte { return m.buf[pos-m.base] }
+
+func (m *matcher) hash(pos int64) uint32 {
+	i := pos - m.base
+	v := uint32(m.buf[i]) | uint32(m.buf[i+1])<<8 | uint32(m.buf[i+2])<<16
+	return (v * hashMul) >> (32 - hashBits)
+}
+
+// insert добавляет позицию в хэш-цепочку
+func (m *matcher) insert(pos int64) {
+	if pos+minMatch > m.end() {
+		return
+	}
+	h := m.hash(pos)
+	m.prev[pos%int64(m.window)] = m.head[h]
+	m.head[h] = pos
+}
+
+// find возвращает самое длинное совпадение для позиции pos
+func (m *matcher) find(pos int64) (offset int, length int) {
+	if pos+minMatch > m.end() {
+		return 0, 0
+	}
+	limit := min(int64(maxMatch), m.end()-pos)
+	cand := m.head[m.hash(pos)]
+	for chain := 0; cand != noPos && chain < m.maxChain; chain++ {
+		if pos-cand > int64(m.window) {
+			break
+		}
+		l := int64(0)
+		for l < limit && m.at(cand+l) == m.at(pos+l) {
+			l++
+		}
+		if int(l) > length {
+			offset, length = int(pos-cand), int(l)
+			if l == limit {
+				break
+			}
+		}
+		next := m.prev[cand%int64(m.window)]
+		if next >= cand {
+			break
+		}
+		cand = next
+	}
+	return offset, length
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"io"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// CompressionType хранит файлы без сжатия. Данных в футере нет.
+const CompressionType = "STORE"
+
+type Compressor struct{}
+
+func NewCompressor() *Compressor { return &Compressor{} }
+
+// Preprocessing возвращает размеры файлов: сохраненный файл совпадает с исходным.
+func (c *Compressor) Preprocessing(srcs []io.Reader) ([]int64, error) {
+	var eg errgroup.Group
+	sizes := make([]int64, len(srcs))
+	for i, src := range srcs {
+		eg.Go(func() (err error) {
+			sizes[i], err = io.Copy(io.Discard, src)
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return sizes, nil
+}
+
+func (c *Compressor) CompressorData() (string, comp.Body) { return CompressionType, nil }
+
+func (c *Compressor) CompressFile(
+	src io.Reader, dst io.Writer, prog *utiles.Progress[int64],
+) (size int64, trailingBits uint8, err error) {
+	size, err = io.Copy(dst, &utiles.ProgressReader{R: src, Prog: prog})
+	return size, 0, err
+}
@@ -0,0 +1,27 @@
+package store
+
+import (
+	comp "compressor/internal/compressing"
+	"compressor/internal/utiles"
+	"fmt"
+	"io"
+)
+
+type Decompressor struct{}
+
+func NewDecompressor() *Decompressor { return &Decompressor{} }
+
+func (d *Decompressor) FooterBodyType() comp.Body { return nil }
+
+func (d *Decompressor) Preprocessing(_ comp.Body, _ io.ReadSeeker) error { return nil }
+
+func (d *Decompressor) DecompressFile(dd *comp.DecompressionInput, prog *utiles.Progress[int64]) error {
+	n, err := io.Copy(dd.DestFile, &utiles.ProgressReader{R: dd.SourceFile, Prog: prog})
+	if err != nil {
+		return err
+	}
+	if n != dd.Entry.Size {
+		return fmt.Errorf("stored file is truncated: %d of %d bytes", n, dd.Entry.Size)
+	}
+	return nil
+}
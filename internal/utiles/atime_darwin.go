@@ -0,0 +1,17 @@
+package utiles
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// AccessTime returns the last access time, or the modification time
+// if the system doesn't report it.
+func AccessTime(info fs.FileInfo) time.Time {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(st.Atimespec.Sec, st.Atimespec.Nsec)
+}
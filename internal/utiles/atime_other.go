@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package utiles
+
+import (
+	"io/fs"
+	"time"
+)
+
+func AccessTime(info fs.FileInfo) time.Time { return info.ModTime() }
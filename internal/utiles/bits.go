@@ -0,0 +1,140 @@
+package utiles
+
+import (
+	"bufio"
+	"io"
+)
+
+// BitWriter packs bit sequences back to back into the underlying writer,
+// most significant bit first.
+type BitWriter struct {
+	w    *bufio.Writer
+	acc  uint8
+	nacc uint8
+	size int64
+}
+
+func NewBitWriter(w io.Writer) *BitWriter {
+	return &BitWriter{w: bufio.NewWriter(w)}
+}
+
+// WriteBits writes the n lowest bits of bits, starting from the highest of them.
+func (bw *BitWriter) WriteBits(bits uint64, n uint8) error {
+	for n > 0 {
+		take := min(8-bw.nacc, n)
+		chunk := uint8(bits>>(n-take)) & (1<<take - 1)
+		bw.acc = bw.acc<<take | chunk
+		bw.nacc += take
+		n -= take
+
+		if bw.nacc == 8 {
+			if err := bw.w.WriteByte(bw.acc); err != nil {
+				return err
+			}
+			bw.acc, bw.nacc = 0, 0
+			bw.size++
+		}
+	}
+	return nil
+}
+
+// Flush pads the last byte with zeros and flushes the buffer. It returns
+// the number of written bytes and the number of used bits in the last
+// byte (0 when the last byte is full or nothing was written).
+func (bw *BitWriter) Flush() (size int64, trailingBits uint8, err error) {
+	if bw.nacc > 0 {
+		trailingBits = bw.nacc
+		if err := bw.w.WriteByte(bw.acc << (8 - bw.nacc)); err != nil {
+			return 0, 0, err
+		}
+		bw.acc, bw.nacc = 0, 0
+		bw.size++
+	}
+	if err := bw.w.Flush(); err != nil {
+		return 0, 0, err
+	}
+	return bw.size, trailingBits, nil
+}
+
+// BitReader reads a stream written by BitWriter. Bits are loaded into
+// a 64-bit accumulator, so several of them can be peeked at once.
+type BitReader struct {
+	r    *bufio.Reader
+	acc  uint64 // loaded bits in the lowest nacc bits
+	nacc uint8
+	left int64 // bits which are not consumed yet
+}
+
+// NewBitReader returns a reader of at most nbits bits from r.
+func NewBitReader(r io.Reader, nbits int64) *BitReader {
+	return &BitReader{r: bufio.NewReader(r), left: nbits}
+}
+
+func (br *BitReader) fill() error {
+	for br.nacc <= 56 && int64(br.nacc) < br.left {
+		b, err := br.r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		br.acc = br.acc<<8 | uint64(b)
+		br.nacc += 8
+	}
+	return nil
+}
+
+// PeekBits returns the next n (at most 56) bits without consuming them.
+// Bits past the end of the stream are zeros.
+func (br *BitReader) PeekBits(n uint8) (uint64, error) {
+	if br.nacc < n {
+		return br.peekSlow(n)
+	}
+	return (br.acc >> (br.nacc - n)) & (1<<n - 1), nil
+}
+
+func (br *BitReader) peekSlow(n uint8) (uint64, error) {
+	if err := br.fill(); err != nil {
+		return 0, err
+	}
+	mask := uint64(1)<<n - 1
+	if br.nacc < n {
+		return (br.acc << (n - br.nacc)) & mask, nil
+	}
+	return (br.acc >> (br.nacc - n)) & mask, nil
+}
+
+// SkipBits consumes n bits, which must be peeked before.
+func (br *BitReader) SkipBits(n uint8) error {
+	if int64(n) > br.left || n > br.nacc {
+		return io.ErrUnexpectedEOF
+	}
+	br.nacc -= n
+	br.left -= int64(n)
+	return nil
+}
+
+// ReadBits returns the next n (at most 56) bits.
+func (br *BitReader) ReadBits(n uint8) (uint64, error) {
+	bits, err := br.PeekBits(n)
+	if err != nil {
+		return 0, err
+	}
+	return bits, br.SkipBits(n)
+}
+
+// ReadBit returns the next bit or io.EOF when all bits are consumed.
+func (br *BitReader) ReadBit() (uint8, error) {
+	if br.left <= 0 {
+		return 0, io.EOF
+	}
+	bit, err := br.PeekBits(1)
+	if err != nil {
+		return 0, err
+	}
+	return uint8(bit), br.SkipBits(1)
+}
+
+// BitsLeft returns the number of bits which are not read yet.
+func (br *BitReader) BitsLeft() int64 { return br.left }
@@ -1,61 +1,96 @@
-package utiles
-
-import (
-	"errors"
-	"fmt"
-	"io/fs"
-	"os"
-	"path/filepath"
-)
-
-func CloseFiles(files []*os.File) (err error) {
-	errs := make([]error, 0)
-	for _, f := range files {
-		if err = f.Close(); err != nil {
-			errs = append(errs, err)
-		}
-	}
-	if len(errs) != 0 {
-		return errors.Join(errs...)
-	}
-	return nil
-}
-
-func OpenFiles(pathes ...string) (files []*os.File, err error) {
-	files = make([]*os.File, len(pathes))
-	for i, path := range pathes {
-		if files[i], err = os.Open(path); err != nil {
-			if err2 := CloseFiles(files[:i]); err2 != nil {
-				err = fmt.Errorf(
-					"ошибка открытия файла: %w, ошибка при экстренном закрытии файлов %w",
-					err, err2,
-				)
-			}
-			return nil, err
-		}
-	}
-	return files, nil
-}
-
-func GetDirFiles(dirpath string) (pathes []string, err error) {
-	if info, err := os.Stat(dirpath); err != nil {
-		return nil, err
-	} else if !info.IsDir() {
-		return nil, fmt.Errorf("path must be a dir")
-	}
-
-	pathes = make([]string, 0)
-	err = filepath.WalkDir(
-		dirpath,
-		func(path string, dir fs.DirEntry, err error) error {
-			if !dir.IsDir() {
-				pathes = append(pathes, path)
-			}
-			return err
-		},
-	)
-	if err != nil {
-		return nil, err
-	}
-	return pathes, nil
-}
+package utiles
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+func CloseFiles(files []*os.File) (err error) {
+	errs := make([]error, 0)
+	for _, f := range files {
+		if err = f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func OpenFiles(pathes ...string) (files []*os.File, err error) {
+	files = make([]*os.File, len(pathes))
+	for i, path := range pathes {
+		if files[i], err = os.Open(path); err != nil {
+			if err2 := CloseFiles(files[:i]); err2 != nil {
+				err = fmt.Errorf(
+					"ошибка открытия файла: %w, ошибка при экстренном закрытии файлов %w",
+					err, err2,
+				)
+			}
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// GetDirFiles returns all entries under dirpath: files, directories and
+// symlinks (which are not followed). dirpath itself is not included.
+func GetDirFiles(dirpath string) (pathes []string, err error) {
+	if info, err := os.Stat(dirpath); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("path must be a dir")
+	}
+
+	pathes = make([]string, 0)
+	err = filepath.WalkDir(
+		dirpath,
+		func(path string, dir fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path != dirpath {
+				pathes = append(pathes, path)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pathes, nil
+}
+
+// UnixMode переводит права доступа в биты режима Unix (0o7777)
+func UnixMode(mode fs.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		m |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		m |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		m |= 0o1000
+	}
+	return m
+}
+
+// FileMode — обратное преобразование к UnixMode
+func FileMode(mode uint32) fs.FileMode {
+	m := fs.FileMode(mode & 0o777)
+	if mode&0o4000 != 0 {
+		m |= fs.ModeSetuid
+	}
+	if mode&0o2000 != 0 {
+		m |= fs.ModeSetgid
+	}
+	if mode&0o1000 != 0 {
+		m |= fs.ModeSticky
+	}
+	return m
+}
@@ -0,0 +1,41 @@
+package utiles
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether name matches the shell pattern. Both use '/'
+// as the separator. Besides the syntax of path.Match, a "**" element
+// matches any number of path elements, including none.
+func MatchGlob(pattern, name string) (bool, error) {
+	elems := strings.Split(pattern, "/")
+	// ошибка в шаблоне не должна зависеть от того, до какого элемента дошло сравнение
+	for _, elem := range elems {
+		if _, err := path.Match(elem, ""); err != nil {
+			return false, err
+		}
+	}
+	return matchElems(elems, strings.Split(name, "/"))
+}
+
+func matchElems(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if ok, err := matchElems(pattern[1:], name[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		if ok, err := path.Match(pattern[0], name[0]); !ok || err != nil {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
@@ -0,0 +1,29 @@
+package utiles
+
+import "io"
+
+// CountingWriter counts the bytes written to W.
+type CountingWriter struct {
+	W io.Writer
+	N int64
+}
+
+func (w *CountingWriter) Write(p []byte) (int, error) {
+	n, err := w.W.Write(p)
+	w.N += int64(n)
+	return n, err
+}
+
+// ProgressReader reports the bytes read from R to Prog.
+type ProgressReader struct {
+	R    io.Reader
+	Prog *Progress[int64]
+}
+
+func (r *ProgressReader) Read(p []byte) (int, error) {
+	n, err := r.R.Read(p)
+	if n > 0 {
+		r.Prog.Write(int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package utiles
+
+import "io/fs"
+
+func FileOwner(info fs.FileInfo) (uid, gid int, ok bool) { return 0, 0, false }
+
+func FileID(info fs.FileInfo) (dev, ino, links uint64, ok bool) { return 0, 0, 0, false }
@@ -0,0 +1,26 @@
+//go:build unix
+
+package utiles
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// FileOwner returns the owner of the file if the system reports it.
+func FileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
+
+// FileID returns the device and inode of the file and its number of hard links.
+func FileID(info fs.FileInfo) (dev, ino, links uint64, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	return uint64(st.Dev), uint64(st.Ino), uint64(st.Nlink), true
+}